@@ -1,7 +1,7 @@
 from agent_context import AgentContext
 from function_stream import PulsarConfig, SourceSpec
-from pydantic import BaseModel, ConfigDict, field_validator
-from typing import Optional
+from pydantic import BaseModel, ConfigDict, Field, field_validator
+from typing import Dict, Optional
 
 class ModelConfig(BaseModel):
     model: str = "gemini-2.0-flash"
@@ -26,6 +26,27 @@ class SessionServiceConfig(BaseModel):
             raise ValueError("database_url must be a valid database connection string")
         return v
 
+class TracingConfig(BaseModel):
+    endpoint: str
+    protocol: str = "grpc"
+    insecure: bool = False
+    headers: Dict[str, str] = Field(default_factory=dict)
+    serviceName: str = "agent"
+    samplingRatio: float = 1.0
+    resourceAttributes: Dict[str, str] = Field(default_factory=dict)
+
+class LimitsConfig(BaseModel):
+    requestsPerSecond: Optional[int] = None
+    maxInFlight: Optional[int] = None
+    maxToolCallsPerRequest: Optional[int] = None
+    maxTokensPerRequest: Optional[int] = None
+    throttleTopic: Optional[str] = None
+
+class UsageConfig(BaseModel):
+    topic: str
+    namespace: str
+    agent: str
+
 class AgentConfig(BaseModel):
     model_config = ConfigDict(arbitrary_types_allowed=True)
     
@@ -34,4 +55,8 @@ class AgentConfig(BaseModel):
     responseSource: SourceSpec
     model: ModelConfig = ModelConfig()
     sessionService: Optional[SessionServiceConfig] = None
-    app_name: str = "default_app"
\ No newline at end of file
+    app_name: str = "default_app"
+    deadLetterTopic: Optional[str] = None
+    tracing: Optional[TracingConfig] = None
+    limits: Optional[LimitsConfig] = None
+    usage: Optional[UsageConfig] = None
\ No newline at end of file
@@ -2,10 +2,13 @@ import json
 
 from google.adk.tools.base_tool import BaseTool
 from google.adk.tools.tool_context import ToolContext
-from typing import Any, Dict
+from typing import Any, Dict, Optional
 from typing_extensions import override
 from google.genai.types import FunctionDeclaration, Schema, Type, JSONSchema
+from opentelemetry import trace
 from pulsar_rpc import PulsarRPCManager
+from tracing import inject_context, tracer
+from limits import RequestLimiter
 from agent_context import FSFunctionToolContext
 
 
@@ -17,6 +20,7 @@ class FSFunctionTool(BaseTool):
         name: str,
         ctx: FSFunctionToolContext,
         rpc_manager: PulsarRPCManager,
+        limiter: Optional[RequestLimiter] = None,
     ):
         super().__init__(
             name=name,
@@ -25,6 +29,7 @@ class FSFunctionTool(BaseTool):
         self.name = name
         self.ctx = ctx
         self._rpc_manager = rpc_manager
+        self._limiter = limiter
         
     def _get_declaration(self) -> FunctionDeclaration:
         """Get the function declaration for this tool."""
@@ -45,9 +50,16 @@ class FSFunctionTool(BaseTool):
     @override
     async def run_async(self, *, args: dict[str, Any], tool_context: ToolContext) -> Any:
         """Run the tool asynchronously with the given arguments."""
-        if self.ctx.mode == "streaming":
-            return await self._rpc_manager.produce(topic=self.ctx.requestSource, data=args)
-        return await self._rpc_manager.request(
-            topic=self.ctx.requestSource,
-            data=args
-        )
+        if self._limiter:
+            self._limiter.count_tool_call()
+        with tracer.start_as_current_span(f"tool {self.name}", kind=trace.SpanKind.PRODUCER) as span:
+            span.set_attribute("messaging.destination.name", self.ctx.requestSource)
+            # The tool Function continues the trace from the traceparent property
+            properties = inject_context()
+            if self.ctx.mode == "streaming":
+                return await self._rpc_manager.produce(topic=self.ctx.requestSource, data=args, properties=properties)
+            return await self._rpc_manager.request(
+                topic=self.ctx.requestSource,
+                data=args,
+                properties=properties,
+            )
@@ -0,0 +1,175 @@
+"""Enforcement of the limits of an agent and reporting of the requests they throttle.
+
+Requests over the rate or concurrency limit wait until they fit in it. Requests that
+make too many tool calls or use too many model tokens fail. Throttled requests are
+counted and reported to the throttle topic, where the operator picks them up to show
+them in the status of the agent.
+"""
+import asyncio
+import contextvars
+import logging
+import time
+from contextlib import asynccontextmanager
+from typing import Dict, Optional
+
+from config import LimitsConfig
+
+logger = logging.getLogger(__name__)
+
+RATE_LIMITED = "RateLimited"
+CONCURRENCY_LIMITED = "ConcurrencyLimited"
+TOOL_CALL_LIMIT_EXCEEDED = "ToolCallLimitExceeded"
+TOKEN_LIMIT_EXCEEDED = "TokenLimitExceeded"
+
+# How often throttled requests are reported, in seconds
+REPORT_INTERVAL = 10
+
+
+class LimitExceeded(Exception):
+    """Raised when a request exceeds its tool call or token limit."""
+
+    def __init__(self, reason: str, message: str):
+        super().__init__(message)
+        self.reason = reason
+
+
+class RequestUsage:
+    """Tool calls and model tokens used by one request."""
+
+    def __init__(self):
+        self.tool_calls = 0
+        self.tokens = 0
+        self.input_tokens = 0
+        self.output_tokens = 0
+
+
+# Usage of the request being processed in the current task
+_usage: contextvars.ContextVar[Optional[RequestUsage]] = contextvars.ContextVar("request_usage", default=None)
+
+
+class RequestLimiter:
+    """Enforces the limits of an agent across its requests."""
+
+    def __init__(self, config: Optional[LimitsConfig], rpc_manager=None):
+        """
+        Initialize the RequestLimiter.
+
+        Args:
+            config (Optional[LimitsConfig]): The limits to enforce, None for no limits
+            rpc_manager (Optional[PulsarRPCManager]): Used to publish throttle reports
+        """
+        self.config = config or LimitsConfig()
+        self.rpc_manager = rpc_manager
+        self._semaphore = asyncio.Semaphore(self.config.maxInFlight) if self.config.maxInFlight else None
+        self._interval = 1.0 / self.config.requestsPerSecond if self.config.requestsPerSecond else 0.0
+        self._next_start = 0.0
+        self._rate_lock = asyncio.Lock()
+        self._throttled: Dict[str, int] = {}
+        self._report_task = None
+        if self.config.throttleTopic and self.rpc_manager:
+            self._report_task = asyncio.create_task(self._report_loop())
+
+    @asynccontextmanager
+    async def request(self):
+        """Wait until a request fits in the rate and concurrency limits, and track its usage while it runs.
+
+        Yields:
+            RequestUsage: The usage of the request, complete once it returns
+        """
+        if self._semaphore:
+            if self._semaphore.locked():
+                self._throttle(CONCURRENCY_LIMITED)
+            await self._semaphore.acquire()
+        try:
+            await self._wait_for_rate()
+            usage = RequestUsage()
+            token = _usage.set(usage)
+            try:
+                yield usage
+            finally:
+                _usage.reset(token)
+        finally:
+            if self._semaphore:
+                self._semaphore.release()
+
+    async def _wait_for_rate(self):
+        """Wait until the next request may start without exceeding requestsPerSecond."""
+        if not self._interval:
+            return
+        async with self._rate_lock:
+            now = time.monotonic()
+            start = max(now, self._next_start)
+            self._next_start = start + self._interval
+        if start > now:
+            self._throttle(RATE_LIMITED)
+            await asyncio.sleep(start - now)
+
+    def count_tool_call(self):
+        """Count a tool call of the current request, failing it if it exceeds maxToolCallsPerRequest."""
+        usage = _usage.get()
+        if usage is None:
+            return
+        usage.tool_calls += 1
+        limit = self.config.maxToolCallsPerRequest
+        if limit and usage.tool_calls > limit:
+            self._throttle(TOOL_CALL_LIMIT_EXCEEDED)
+            raise LimitExceeded(TOOL_CALL_LIMIT_EXCEEDED, f"Request exceeded the limit of {limit} tool calls")
+
+    def count_tokens(self, tokens: int, input_tokens: int = 0, output_tokens: int = 0):
+        """Count model tokens of the current request, failing it if it exceeds maxTokensPerRequest.
+
+        Args:
+            tokens (int): Total tokens of a model call
+            input_tokens (int): Prompt tokens of the call, reported for budgets
+            output_tokens (int): Response tokens of the call, reported for budgets
+        """
+        usage = _usage.get()
+        if usage is None or not tokens:
+            return
+        usage.tokens += tokens
+        usage.input_tokens += input_tokens
+        usage.output_tokens += output_tokens
+        limit = self.config.maxTokensPerRequest
+        if limit and usage.tokens > limit:
+            self._throttle(TOKEN_LIMIT_EXCEEDED)
+            raise LimitExceeded(TOKEN_LIMIT_EXCEEDED, f"Request used {usage.tokens} tokens, over the limit of {limit}")
+
+    def _throttle(self, reason: str):
+        self._throttled[reason] = self._throttled.get(reason, 0) + 1
+
+    async def _report_loop(self):
+        """Publish the requests throttled in every REPORT_INTERVAL to the throttle topic."""
+        while True:
+            await asyncio.sleep(REPORT_INTERVAL)
+            try:
+                await self.report()
+            except Exception as e:
+                logger.error(f"Failed to report throttled requests: {e}")
+
+    async def report(self):
+        """Publish one report per reason of the requests throttled since the last report."""
+        throttled, self._throttled = self._throttled, {}
+        for reason, count in throttled.items():
+            await self.rpc_manager.produce(self.config.throttleTopic, {
+                "reason": reason,
+                "count": count,
+                "message": _report_messages[reason].format(count=count, config=self.config),
+            })
+
+    async def close(self):
+        """Stop reporting throttled requests."""
+        if self._report_task is not None:
+            self._report_task.cancel()
+            try:
+                await self._report_task
+            except asyncio.CancelledError:
+                pass
+            self._report_task = None
+
+
+_report_messages = {
+    RATE_LIMITED: "{count} requests waited for the limit of {config.requestsPerSecond} requests per second",
+    CONCURRENCY_LIMITED: "{count} requests waited for the limit of {config.maxInFlight} requests in flight",
+    TOOL_CALL_LIMIT_EXCEEDED: "{count} requests failed for exceeding {config.maxToolCallsPerRequest} tool calls",
+    TOKEN_LIMIT_EXCEEDED: "{count} requests failed for exceeding {config.maxTokensPerRequest} tokens",
+}
@@ -10,9 +10,13 @@ from google.adk.tools.tool_context import ToolContext
 from fs_function_tool import FSFunctionTool
 from pulsar_rpc import PulsarRPCManager
 import uuid
+from datetime import datetime, timezone
 import _jsonnet
 import os
 from config import AgentConfig
+from opentelemetry import trace
+from tracing import setup_tracing, require_message_properties, message_properties, extract_context, inject_context, tracer
+from limits import RequestLimiter
 from json_repair import repair_json
 
 
@@ -54,6 +58,8 @@ class AgentFunction(FSModule):
         self.agent_ctx = None
         self.runner = None
         self.outputMap: Dict[str, str] = {}
+        self.tracer_provider = None
+        self.limiter = None
 
     def output_tool(self, message: dict, tool_context: ToolContext) -> dict:
         """A tool for output message. If users ask you to output messages, you SHOULD use this tool. The message MUST be a json format.
@@ -67,7 +73,9 @@ class AgentFunction(FSModule):
         return {"result": "success"}
 
     def init(self, context: FSContext):
+        require_message_properties(context)
         self.config = AgentConfig.model_validate(context.get_configs())
+        self.tracer_provider = setup_tracing(self.config.tracing)
         
         # Extract auth parameters from PulsarConfig if available
         auth_plugin = self.config.pulsarRpc.authPlugin
@@ -96,6 +104,7 @@ class AgentFunction(FSModule):
             self.session_service = InMemorySessionService()
         
         self.runner = None
+        self.limiter = RequestLimiter(self.config.limits, self.rpc_manager)
 
         if self.config.model.googleApiKey:
             os.environ["GOOGLE_API_KEY"] = self.config.model.googleApiKey
@@ -103,7 +112,7 @@ class AgentFunction(FSModule):
         tools = []
         self.agent_ctx = self.config.agent
         for n, f in self.agent_ctx.tools.items():
-            tools.append(FSFunctionTool(name=n, ctx=f, rpc_manager=self.rpc_manager))
+            tools.append(FSFunctionTool(name=n, ctx=f, rpc_manager=self.rpc_manager, limiter=self.limiter))
         tools.append(self.output_tool)
         root_agent = Agent(
             name=self.agent_ctx.name,
@@ -115,6 +124,74 @@ class AgentFunction(FSModule):
         self.runner = Runner(agent=root_agent, app_name=self.config.app_name, session_service=self.session_service)
 
     async def process(self, context: FSContext, data: Dict[str, Any]) -> Dict[str, Any] | None:
+        properties = message_properties(context)
+        # Tool calls made while processing become children of this span
+        with tracer.start_as_current_span(
+            f"agent {self.agent_ctx.name}",
+            context=extract_context(properties),
+            kind=trace.SpanKind.CONSUMER,
+        ) as span:
+            if properties.get('request_id'):
+                span.set_attribute("messaging.message.id", properties['request_id'])
+            try:
+                async with self.limiter.request() as usage:
+                    try:
+                        return await self._process(context, data)
+                    finally:
+                        # Tokens of failed requests are spent too
+                        await self._report_usage(usage)
+            except Exception as e:
+                await self._dead_letter(data, e, properties)
+                raise
+
+    async def _report_usage(self, usage):
+        """Publish the model tokens a request used to the usage topic, where the operator counts them against budgets.
+
+        Args:
+            usage (RequestUsage): The usage of the request
+        """
+        if not self.config or not self.config.usage or not self.rpc_manager or not usage.tokens:
+            return
+        report = {
+            'namespace': self.config.usage.namespace,
+            'agent': self.config.usage.agent,
+            'model': self.config.model.model,
+            'inputTokens': usage.input_tokens,
+            'outputTokens': usage.output_tokens,
+        }
+        try:
+            await self.rpc_manager.produce(self.config.usage.topic, report)
+        except Exception as e:
+            # Failing the request would not give the tokens back
+            print(f"Failed to report usage to topic {self.config.usage.topic}: {e}")
+
+    async def _dead_letter(self, data: Dict[str, Any], error: Exception, request_properties: Dict[str, str]):
+        """Publish a request that failed to the dead-letter topic, with its properties and the error.
+
+        Args:
+            data (Dict[str, Any]): The request that failed
+            error (Exception): The error raised while processing it
+            request_properties (Dict[str, str]): The properties of the request, such as request_id
+                and response_topic, so that replays are answered like the request
+        """
+        if not self.config or not self.config.deadLetterTopic or not self.rpc_manager:
+            return
+        properties = {
+            **request_properties,
+            'error': str(error),
+            'error_type': type(error).__name__,
+            'agent': self.agent_ctx.name if self.agent_ctx else '',
+            'failed_at': datetime.now(timezone.utc).isoformat(),
+        }
+        # Keep the trace of the failed request, so replays can be followed in it
+        properties = inject_context(properties)
+        try:
+            await self.rpc_manager.produce(self.config.deadLetterTopic, data, properties=properties)
+        except Exception as e:
+            # The original error is more useful to the caller than this one
+            print(f"Failed to publish request to dead-letter topic {self.config.deadLetterTopic}: {e}")
+
+    async def _process(self, context: FSContext, data: Dict[str, Any]) -> Dict[str, Any] | None:
         input = json.dumps(data, ensure_ascii=False)
         content = types.Content(role='user',
                                 parts=[types.Part(text=input)])
@@ -127,6 +204,11 @@ class AgentFunction(FSModule):
         final_response = None
         agent_event_generator = self.runner.run_async(user_id=user_id, session_id=session_id, new_message=content)
         async for event in agent_event_generator:
+            usage = getattr(event, 'usage_metadata', None)
+            if usage and usage.total_token_count:
+                self.limiter.count_tokens(usage.total_token_count,
+                                          input_tokens=usage.prompt_token_count or 0,
+                                          output_tokens=usage.candidates_token_count or 0)
             if event.is_final_response():
                 final_response = event.content.parts[0].text
                 break
@@ -163,8 +245,12 @@ local agent_output = {repaired_output};
             await self.runner.close()
         if self.session_service:
             await self.session_service.close()
+        if self.limiter:
+            await self.limiter.close()
         if self.rpc_manager:
             await self.rpc_manager.close()
+        if self.tracer_provider:
+            self.tracer_provider.shutdown()
 
 
 async def main():
@@ -111,7 +111,7 @@ class PulsarRPCManager:
                 logger.error(f"Error in response processing loop: {str(e)}")
                 await asyncio.sleep(1)  # Prevent tight loop in case of errors
 
-    async def produce(self, topic:str, data: Any):
+    async def produce(self, topic:str, data: Any, properties: Optional[Dict[str, str]] = None):
         try:
             loop = asyncio.get_event_loop()
             # Get a cached producer for this topic
@@ -135,19 +135,21 @@ class PulsarRPCManager:
             producer.send_async(
                 message_data,
                 callback,
+                properties=properties,
             )
             await future
         except Exception as e:
             logger.error(f"Error in producing message: {str(e)}")
             raise
 
-    async def request(self, topic: str, data: Any) -> Dict[str, Any]:
+    async def request(self, topic: str, data: Any, properties: Optional[Dict[str, str]] = None) -> Dict[str, Any]:
         """
         Make an RPC request to the specified topic.
 
         Args:
             topic (str): The topic to send the request to
             data (Any): The data to send (will be converted to JSON)
+            properties (Optional[Dict[str, str]]): Additional message properties, e.g. the traceparent
 
         Returns:
             Dict[str, Any]: The response data
@@ -175,15 +177,14 @@ class PulsarRPCManager:
             message_data = json.dumps(data).encode('utf-8')
             
             # Set message properties
-            properties = {
-                'request_id': request_id,
-                'response_topic': self.response_topic
-            }
+            message_properties = dict(properties or {})
+            message_properties['request_id'] = request_id
+            message_properties['response_topic'] = self.response_topic
             
             # Send the message
             producer.send(
                 message_data,
-                properties=properties
+                properties=message_properties
             )
             
             # Wait for the response
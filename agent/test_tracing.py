@@ -0,0 +1,47 @@
+import pytest
+
+from tracing import extract_context, inject_context, message_properties, require_message_properties
+from opentelemetry import trace
+
+TRACEPARENT = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+
+class FakeContext:
+    """A function-stream context exposing the properties of the current message"""
+
+    def __init__(self, properties):
+        self.properties = properties
+
+    def get_properties(self):
+        return self.properties
+
+
+class TestMessageProperties:
+
+    def test_returns_properties_of_context(self):
+        properties = {"request_id": "1", "traceparent": TRACEPARENT}
+        assert message_properties(FakeContext(properties)) == properties
+
+    def test_none_properties(self):
+        assert message_properties(FakeContext(None)) == {}
+
+    def test_failing_context(self):
+        class FailingContext:
+            def get_properties(self):
+                raise RuntimeError("no message")
+
+        assert message_properties(FailingContext()) == {}
+
+    def test_requires_get_properties(self):
+        require_message_properties(FakeContext({}))
+        with pytest.raises(RuntimeError, match="get_properties"):
+            require_message_properties(object())
+
+    def test_continues_trace_of_request(self):
+        properties = message_properties(FakeContext({"traceparent": TRACEPARENT}))
+        span_context = trace.get_current_span(extract_context(properties)).get_span_context()
+        assert format(span_context.trace_id, "032x") == "0af7651916cd43dd8448eb211c80319c"
+        assert format(span_context.span_id, "016x") == "b7ad6b7169203331"
+
+    def test_inject_keeps_properties(self):
+        assert inject_context({"request_id": "1"})["request_id"] == "1"
@@ -0,0 +1,91 @@
+"""OpenTelemetry tracing of agent requests and tool calls.
+
+The trace context travels between the CLI, the agent and tool Functions in the W3C
+traceparent message property. Without a tracing configuration no spans are exported,
+but the context of incoming requests is still passed on to tool calls.
+"""
+import logging
+from typing import Any, Dict, Optional
+
+from opentelemetry import context as otel_context
+from opentelemetry import trace
+from opentelemetry.propagators.tracecontext import TraceContextTextMapPropagator
+from opentelemetry.sdk.resources import Resource
+from opentelemetry.sdk.trace import TracerProvider
+from opentelemetry.sdk.trace.export import BatchSpanProcessor
+from opentelemetry.sdk.trace.sampling import ParentBased, TraceIdRatioBased
+
+from config import TracingConfig
+
+logger = logging.getLogger(__name__)
+
+_propagator = TraceContextTextMapPropagator()
+
+tracer = trace.get_tracer("agentstream.agent")
+
+
+def setup_tracing(config: Optional[TracingConfig]) -> Optional[TracerProvider]:
+    """Install a tracer provider exporting spans to the configured OTLP endpoint.
+
+    Args:
+        config (Optional[TracingConfig]): The tracing configuration, None to disable export
+
+    Returns:
+        Optional[TracerProvider]: The installed provider, to be shut down on close
+    """
+    if not config:
+        return None
+
+    if config.protocol == "http/protobuf":
+        from opentelemetry.exporter.otlp.proto.http.trace_exporter import OTLPSpanExporter
+        exporter = OTLPSpanExporter(endpoint=config.endpoint, headers=config.headers)
+    else:
+        from opentelemetry.exporter.otlp.proto.grpc.trace_exporter import OTLPSpanExporter
+        exporter = OTLPSpanExporter(endpoint=config.endpoint, insecure=config.insecure, headers=config.headers)
+
+    resource = Resource.create({"service.name": config.serviceName, **config.resourceAttributes})
+    # Requests that already carry a trace follow the sampling decision of the caller
+    sampler = ParentBased(TraceIdRatioBased(config.samplingRatio))
+    provider = TracerProvider(resource=resource, sampler=sampler)
+    provider.add_span_processor(BatchSpanProcessor(exporter))
+    trace.set_tracer_provider(provider)
+    logger.info(f"Exporting traces to {config.endpoint} over {config.protocol}")
+    return provider
+
+
+def require_message_properties(context: Any):
+    """Fail unless the function-stream context exposes the message properties.
+
+    The agent needs get_properties of the context to continue the trace of a request
+    and to keep its request_id and response_topic in dead letters.
+
+    Raises:
+        RuntimeError: If the context has no get_properties
+    """
+    if not callable(getattr(context, "get_properties", None)):
+        raise RuntimeError("The function-stream context has no get_properties, which the agent needs to read "
+                           "the properties of requests. Use a function-stream SDK that provides it")
+
+
+def message_properties(context: Any) -> Dict[str, str]:
+    """Return the properties of the message being processed.
+
+    The context is checked by require_message_properties when the agent starts.
+    """
+    try:
+        return dict(context.get_properties() or {})
+    except Exception as e:
+        logger.warning(f"Failed to read message properties: {e}")
+        return {}
+
+
+def extract_context(properties: Dict[str, str]) -> otel_context.Context:
+    """Return the trace context carried in the traceparent property of a message."""
+    return _propagator.extract(carrier=properties)
+
+
+def inject_context(properties: Optional[Dict[str, str]] = None) -> Dict[str, str]:
+    """Return the properties with the traceparent of the current span added."""
+    carrier = dict(properties or {})
+    _propagator.inject(carrier)
+    return carrier
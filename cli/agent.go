@@ -0,0 +1,669 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	fsv1alpha1 "github.com/FunctionStream/function-stream/operator/api/v1alpha1"
+	asv1alpha1 "github.com/agentstream/agentstream/operator/api/v1alpha1"
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	agentKubeOpts kubeOptions
+
+	agentAllNamespaces bool
+	agentListOutput    string
+	agentGetOutput     string
+
+	agentDisplayName     string
+	agentDescription     string
+	agentInstruction     string
+	agentModel           string
+	agentGoogleAPIKey    string
+	agentSubscription    string
+	agentRequestTopic    string
+	agentResponseTopic   string
+	agentSourceTopics    []string
+	agentSinkTopic       string
+	agentTools           []string
+	agentPostProcessFile string
+	agentDryRun          bool
+
+	agentIgnoreNotFound bool
+
+	agentInvokeJSON          string
+	agentInvokeResponseTopic string
+	agentInvokeKey           string
+	agentInvokeOrderKey      string
+	agentInvokeProperties    []string
+	agentInvokeTimeout       time.Duration
+	agentInvokePulsarURL     string
+	agentInvokeAuth          authFlags
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Manage Agent resources in Kubernetes",
+	Long: `Manage AgentStream Agent resources through the Kubernetes API.
+
+The cluster is selected from the kubeconfig (KUBECONFIG or ~/.kube/config) and can be
+overridden with --kubeconfig, --kube-context and --namespace.
+
+Examples:
+  ascli agent list
+  ascli agent list --all-namespaces
+  ascli agent get my-agent -o yaml
+  ascli agent describe my-agent
+  ascli agent create my-agent --model gemini-2.0-flash --instruction "You are a helpful assistant" --request-topic my-agent-requests --tool weather
+  ascli agent delete my-agent
+  ascli agent suspend my-agent
+  ascli agent resume my-agent
+  ascli agent invoke my-agent --json '{"question": "What is the weather in Paris?"}'`,
+}
+
+var listAgentCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List agents",
+	Args:  cobra.NoArgs,
+	RunE:  runListAgent,
+}
+
+var getAgentCmd = &cobra.Command{
+	Use:               "get [name]",
+	Short:             "Print an agent resource",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeAgentNames(&agentKubeOpts, 1),
+	RunE:              runGetAgent,
+}
+
+var describeAgentCmd = &cobra.Command{
+	Use:               "describe [name]",
+	Short:             "Show an agent with its resolved topics, tools and conditions",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeAgentNames(&agentKubeOpts, 1),
+	RunE:              runDescribeAgent,
+}
+
+var createAgentCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create an agent from flags",
+	Long: `Create an Agent resource from flags.
+
+Tools are referenced by Function name, as 'name' for the agent's namespace or
+'namespace/name'. If --response-topic is not set, the operator assigns a
+non-persistent response topic. Use --dry-run to print the resource instead of
+creating it.
+
+Examples:
+  ascli agent create my-agent --model gemini-2.0-flash --instruction "You are a helpful assistant" --request-topic my-agent-requests
+  ascli agent create my-agent --model gemini-2.0-flash --instruction "Answer weather questions" --request-topic weather-requests --tool weather --tool tools/geocode
+  ascli agent create my-agent --model gemini-2.0-flash --instruction "..." --request-topic my-agent-requests --dry-run > agent.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCreateAgent,
+}
+
+var deleteAgentCmd = &cobra.Command{
+	Use:               "delete [name...]",
+	Short:             "Delete agents",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeAgentNames(&agentKubeOpts, -1),
+	RunE:              runDeleteAgent,
+}
+
+var suspendAgentCmd = &cobra.Command{
+	Use:   "suspend [name...]",
+	Short: "Suspend agents without deleting them",
+	Long: `Suspend agents by setting spec.suspended.
+
+The operator deletes the Function of a suspended agent, so it stops consuming
+requests. The agent and its response topic are kept, and requests wait on its
+subscription until the agent is resumed.
+
+Examples:
+  ascli agent suspend my-agent
+  ascli agent suspend my-agent other-agent -n agents`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeAgentNames(&agentKubeOpts, -1),
+	RunE:              runSuspendAgent,
+}
+
+var resumeAgentCmd = &cobra.Command{
+	Use:               "resume [name...]",
+	Short:             "Resume suspended agents",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeAgentNames(&agentKubeOpts, -1),
+	RunE:              runResumeAgent,
+}
+
+var invokeAgentCmd = &cobra.Command{
+	Use:   "invoke [name]",
+	Short: "Send a request to an agent and print its reply",
+	Long: `Send a request to an agent and print its reply.
+
+The request topic and the response topic are read from the Agent resource
+(spec.requestSource and spec.responseSource), so they don't need to be looked up
+by hand. The reply is matched to the request by its request_id property.
+
+Examples:
+  ascli agent invoke my-agent --json '{"question": "What is the weather in Paris?"}'
+  echo '{"question": "..."}' | ascli agent invoke my-agent --json -
+  ascli agent invoke my-agent -n agents --json '{"question": "..."}' --timeout 2m`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeAgentNames(&agentKubeOpts, 1),
+	RunE:              runInvokeAgent,
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.AddCommand(listAgentCmd)
+	agentCmd.AddCommand(getAgentCmd)
+	agentCmd.AddCommand(describeAgentCmd)
+	agentCmd.AddCommand(createAgentCmd)
+	agentCmd.AddCommand(deleteAgentCmd)
+	agentCmd.AddCommand(suspendAgentCmd)
+	agentCmd.AddCommand(resumeAgentCmd)
+	agentCmd.AddCommand(invokeAgentCmd)
+
+	addKubeFlags(agentCmd, &agentKubeOpts)
+
+	listAgentCmd.Flags().BoolVarP(&agentAllNamespaces, "all-namespaces", "A", false, "List agents in all namespaces")
+	listAgentCmd.Flags().StringVarP(&agentListOutput, "output", "o", "table", "Output format: table, json or yaml")
+	getAgentCmd.Flags().StringVarP(&agentGetOutput, "output", "o", "yaml", "Output format: json or yaml")
+
+	createAgentCmd.Flags().StringVar(&agentDisplayName, "display-name", "", "Display name of the agent")
+	createAgentCmd.Flags().StringVar(&agentDescription, "description", "", "Description of the agent")
+	createAgentCmd.Flags().StringVar(&agentInstruction, "instruction", "", "Instruction for the agent (required)")
+	createAgentCmd.Flags().StringVar(&agentModel, "model", "", "Model name (the default model of the operator if not set)")
+	createAgentCmd.Flags().StringVar(&agentGoogleAPIKey, "google-api-key", "", "Google API key for the model")
+	createAgentCmd.Flags().StringVar(&agentSubscription, "subscription-name", "", "Subscription name used by the agent")
+	createAgentCmd.Flags().StringVar(&agentRequestTopic, "request-topic", "", "Topic the agent receives requests on")
+	createAgentCmd.Flags().StringVar(&agentResponseTopic, "response-topic", "", "Topic the agent receives tool responses on (assigned by the operator if not set)")
+	createAgentCmd.Flags().StringArrayVar(&agentSourceTopics, "source-topic", nil, "Additional source topic (repeatable)")
+	createAgentCmd.Flags().StringVar(&agentSinkTopic, "sink-topic", "", "Topic the agent publishes results to")
+	createAgentCmd.Flags().StringArrayVar(&agentTools, "tool", nil, "Tool function as name or namespace/name (repeatable)")
+	createAgentCmd.Flags().StringVar(&agentPostProcessFile, "post-process-file", "", "Jsonnet file used to post-process agent output")
+	createAgentCmd.Flags().BoolVar(&agentDryRun, "dry-run", false, "Print the agent resource as YAML instead of creating it")
+	createAgentCmd.MarkFlagRequired("instruction")
+
+	deleteAgentCmd.Flags().BoolVar(&agentIgnoreNotFound, "ignore-not-found", false, "Do not fail if an agent does not exist")
+
+	invokeAgentCmd.Flags().StringVar(&agentInvokeJSON, "json", "", "JSON request to send, or '-' to read from stdin (required)")
+	invokeAgentCmd.Flags().StringVar(&agentInvokeResponseTopic, "response-topic", "", "Response topic (defaults to the agent's spec.responseSource topic)")
+	invokeAgentCmd.Flags().StringVar(&agentInvokeKey, "key", "", "Message key, used for partition routing and compaction")
+	invokeAgentCmd.Flags().StringVar(&agentInvokeOrderKey, "ordering-key", "", "Message ordering key, used by Key_Shared subscriptions")
+	invokeAgentCmd.Flags().StringArrayVar(&agentInvokeProperties, "property", nil, "Message property in key=value format (repeatable, request_id and response_topic are set automatically)")
+	invokeAgentCmd.Flags().DurationVar(&agentInvokeTimeout, "timeout", 60*time.Second, "Maximum time to wait for the reply")
+	invokeAgentCmd.Flags().StringVar(&agentInvokePulsarURL, "pulsar-url", "", "Service URL (overrides context)")
+	addAuthFlags(invokeAgentCmd, &agentInvokeAuth)
+	invokeAgentCmd.MarkFlagRequired("json")
+}
+
+// parseToolRef parses a tool reference in the name or namespace/name form
+func parseToolRef(value string) (asv1alpha1.NamespacedName, error) {
+	namespace, name, found := strings.Cut(value, "/")
+	if !found {
+		name, namespace = namespace, ""
+	}
+	if name == "" || strings.Contains(name, "/") {
+		return asv1alpha1.NamespacedName{}, fmt.Errorf("invalid tool reference %q, expected name or namespace/name", value)
+	}
+	ref := asv1alpha1.NamespacedName{Name: name}
+	if namespace != "" {
+		ref.Namespace = &namespace
+	}
+	return ref, nil
+}
+
+// buildAgentFromFlags builds an Agent resource from the create flags
+func buildAgentFromFlags(name, namespace string) (*asv1alpha1.Agent, error) {
+	a := &asv1alpha1.Agent{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: agentGVR.GroupVersion().String(),
+			Kind:       "Agent",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: asv1alpha1.AgentSpec{
+			DisplayName:      agentDisplayName,
+			Description:      agentDescription,
+			Instruction:      agentInstruction,
+			Model:            asv1alpha1.ModelConfig{Model: agentModel, GoogleApiKey: agentGoogleAPIKey},
+			SubscriptionName: agentSubscription,
+		},
+	}
+
+	if agentRequestTopic != "" {
+		a.Spec.RequestSource = &fsv1alpha1.SourceSpec{Pulsar: &fsv1alpha1.PulsarSourceSpec{Topic: agentRequestTopic}}
+	}
+	if agentResponseTopic != "" {
+		a.Spec.ResponseSource = &fsv1alpha1.SourceSpec{Pulsar: &fsv1alpha1.PulsarSourceSpec{Topic: agentResponseTopic}}
+	}
+	for _, topic := range agentSourceTopics {
+		a.Spec.Sources = append(a.Spec.Sources, fsv1alpha1.SourceSpec{Pulsar: &fsv1alpha1.PulsarSourceSpec{Topic: topic}})
+	}
+	if agentSinkTopic != "" {
+		a.Spec.Sink = &fsv1alpha1.SinkSpec{Pulsar: &fsv1alpha1.PulsarSinkSpec{Topic: agentSinkTopic}}
+	}
+	for _, value := range agentTools {
+		ref, err := parseToolRef(value)
+		if err != nil {
+			return nil, err
+		}
+		a.Spec.Tools = append(a.Spec.Tools, ref)
+	}
+	if agentPostProcessFile != "" {
+		data, err := os.ReadFile(agentPostProcessFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read post-process file: %v", err)
+		}
+		a.Spec.PostProcess = &asv1alpha1.PostProcessCallback{Jsonnet: string(data)}
+	}
+	return a, nil
+}
+
+// printObject prints a resource as JSON or YAML
+func printObject(obj interface{}, format string) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+	return nil
+}
+
+// formatAge formats the time since t like kubectl does
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// valueOrNone returns s, or <none> if it is empty
+func valueOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+// agentModelName returns the model of an agent, which is the operator's default if not set
+func agentModelName(a *asv1alpha1.Agent) string {
+	if a.Spec.Model.Model == "" {
+		return "<default>"
+	}
+	return a.Spec.Model.Model
+}
+
+// limitOrNone formats an optional limit
+func limitOrNone[T int32 | int64](limit *T) string {
+	if limit == nil {
+		return "<none>"
+	}
+	return fmt.Sprintf("%d", *limit)
+}
+
+// agentCondition is a condition derived from the agent's function status
+type agentCondition struct {
+	Type    string
+	Status  bool
+	Message string
+}
+
+// agentConditions derives the agent conditions from the replica counts of its function
+func agentConditions(s fsv1alpha1.FunctionStatus) []agentCondition {
+	return []agentCondition{
+		{
+			Type:    "Available",
+			Status:  s.AvailableReplicas > 0,
+			Message: fmt.Sprintf("%d of %d replicas available", s.AvailableReplicas, s.Replicas),
+		},
+		{
+			Type:    "Ready",
+			Status:  s.Replicas > 0 && s.ReadyReplicas == s.Replicas,
+			Message: fmt.Sprintf("%d of %d replicas ready", s.ReadyReplicas, s.Replicas),
+		},
+		{
+			Type:    "UpToDate",
+			Status:  s.UpdatedReplicas == s.Replicas,
+			Message: fmt.Sprintf("%d of %d replicas updated", s.UpdatedReplicas, s.Replicas),
+		},
+	}
+}
+
+func runListAgent(cmd *cobra.Command, args []string) error {
+	client, err := newKubeClient(agentKubeOpts)
+	if err != nil {
+		return err
+	}
+
+	namespace := client.namespace
+	if agentAllNamespaces {
+		namespace = ""
+	}
+	agents, err := client.listAgents(context.Background(), namespace)
+	if err != nil {
+		return fmt.Errorf("failed to list agents: %v", err)
+	}
+
+	if agentListOutput != "table" {
+		return printObject(agents, agentListOutput)
+	}
+
+	if len(agents) == 0 {
+		fmt.Println("No agents found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if agentAllNamespaces {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, "NAME\tMODEL\tREQUEST TOPIC\tTOOLS\tREADY\tAGE")
+	for _, a := range agents {
+		if agentAllNamespaces {
+			fmt.Fprintf(w, "%s\t", a.Namespace)
+		}
+		status := a.Status.FunctionStatus
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d/%d\t%s\n",
+			a.Name,
+			agentModelName(&a),
+			valueOrNone(sourceTopic(a.Spec.RequestSource)),
+			len(a.Spec.Tools),
+			status.ReadyReplicas, status.Replicas,
+			formatAge(a.CreationTimestamp.Time))
+	}
+	return w.Flush()
+}
+
+func runGetAgent(cmd *cobra.Command, args []string) error {
+	client, err := newKubeClient(agentKubeOpts)
+	if err != nil {
+		return err
+	}
+
+	a, err := client.getAgent(context.Background(), client.namespace, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get agent '%s': %v", args[0], err)
+	}
+	return printObject(a, agentGetOutput)
+}
+
+func runDescribeAgent(cmd *cobra.Command, args []string) error {
+	client, err := newKubeClient(agentKubeOpts)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	a, err := client.getAgent(ctx, client.namespace, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get agent '%s': %v", args[0], err)
+	}
+
+	fmt.Printf("Name: %s\n", a.Name)
+	fmt.Printf("Namespace: %s\n", a.Namespace)
+	if a.Spec.DisplayName != "" {
+		fmt.Printf("Display Name: %s\n", a.Spec.DisplayName)
+	}
+	if a.Spec.Description != "" {
+		fmt.Printf("Description: %s\n", a.Spec.Description)
+	}
+	fmt.Printf("Model: %s\n", agentModelName(a))
+	if c := meta.FindStatusCondition(a.Status.Conditions, "Suspended"); c != nil && c.Status == metav1.ConditionTrue {
+		fmt.Printf("Suspended: %s\n", c.Message)
+	} else if a.Spec.Suspended {
+		fmt.Println("Suspended: requested")
+	}
+	fmt.Printf("Created: %s (%s ago)\n", a.CreationTimestamp.Format(time.RFC3339), formatAge(a.CreationTimestamp.Time))
+
+	fmt.Println("\nTopics:")
+	fmt.Printf("  Request:  %s\n", valueOrNone(sourceTopic(a.Spec.RequestSource)))
+	fmt.Printf("  Response: %s\n", valueOrNone(sourceTopic(a.Spec.ResponseSource)))
+	sinkTopic := ""
+	if a.Spec.Sink != nil && a.Spec.Sink.Pulsar != nil {
+		sinkTopic = a.Spec.Sink.Pulsar.Topic
+	}
+	fmt.Printf("  Sink:     %s\n", valueOrNone(sinkTopic))
+	fmt.Printf("  Dead Letter: %s\n", agentDeadLetterTopic(a))
+	for _, source := range a.Spec.Sources {
+		fmt.Printf("  Source:   %s\n", valueOrNone(sourceTopic(&source)))
+	}
+	if a.Spec.SubscriptionName != "" {
+		fmt.Printf("  Subscription: %s\n", a.Spec.SubscriptionName)
+	}
+
+	fmt.Println("\nInstruction:")
+	for _, line := range strings.Split(strings.TrimRight(a.Spec.Instruction, "\n"), "\n") {
+		fmt.Printf("  %s\n", line)
+	}
+
+	fmt.Println("\nTools:")
+	if len(a.Spec.Tools) == 0 {
+		fmt.Println("  <none>")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "  NAME\tREQUEST TOPIC\tMODULE\tREADY")
+		for _, tool := range a.Spec.Tools {
+			f, err := client.getFunction(ctx, tool.GetNamespacedName(a.Namespace).Namespace, tool.Name)
+			if err != nil {
+				fmt.Fprintf(w, "  %s\t<error: %v>\t\t\n", tool.String(), err)
+				continue
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s/%s\t%d/%d\n",
+				tool.String(),
+				valueOrNone(sourceTopic(f.Spec.RequestSource)),
+				f.Spec.PackageRef.Name, f.Spec.Module,
+				f.Status.ReadyReplicas, f.Status.Replicas)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if a.Spec.PostProcess != nil && a.Spec.PostProcess.Jsonnet != "" {
+		fmt.Println("\nPost Process: jsonnet")
+	}
+
+	if l := a.Spec.Limits; l != nil {
+		fmt.Println("\nLimits:")
+		fmt.Printf("  Requests Per Second:     %s\n", limitOrNone(l.RequestsPerSecond))
+		fmt.Printf("  Max In Flight:           %s\n", limitOrNone(l.MaxInFlight))
+		fmt.Printf("  Max Tool Calls/Request:  %s\n", limitOrNone(l.MaxToolCallsPerRequest))
+		fmt.Printf("  Max Tokens/Request:      %s\n", limitOrNone(l.MaxTokensPerRequest))
+		if t := a.Status.Throttling; t != nil {
+			fmt.Printf("  Last Throttled:          %s (%s ago), %s: %s\n",
+				t.LastThrottleTime.Format(time.RFC3339), formatAge(t.LastThrottleTime.Time), t.Reason, valueOrNone(t.Message))
+		}
+	}
+
+	fmt.Println("\nConditions:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  TYPE\tSTATUS\tMESSAGE")
+	for _, c := range agentConditions(a.Status.FunctionStatus) {
+		status := "False"
+		if c.Status {
+			status = "True"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", c.Type, status, c.Message)
+	}
+	// Conditions set by the operator
+	for _, c := range a.Status.Conditions {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", c.Type, c.Status, c.Message)
+	}
+	return w.Flush()
+}
+
+func runCreateAgent(cmd *cobra.Command, args []string) error {
+	if agentDryRun {
+		a, err := buildAgentFromFlags(args[0], agentKubeOpts.namespace)
+		if err != nil {
+			return err
+		}
+		return printObject(a, "yaml")
+	}
+
+	client, err := newKubeClient(agentKubeOpts)
+	if err != nil {
+		return err
+	}
+
+	a, err := buildAgentFromFlags(args[0], client.namespace)
+	if err != nil {
+		return err
+	}
+
+	created, err := client.createAgent(context.Background(), a)
+	if err != nil {
+		return fmt.Errorf("failed to create agent '%s': %v", a.Name, err)
+	}
+
+	fmt.Printf("Agent '%s' created in namespace '%s'\n", created.Name, created.Namespace)
+	return nil
+}
+
+func runDeleteAgent(cmd *cobra.Command, args []string) error {
+	client, err := newKubeClient(agentKubeOpts)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range args {
+		if err := client.deleteAgent(context.Background(), client.namespace, name); err != nil {
+			if agentIgnoreNotFound && apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to delete agent '%s': %v", name, err)
+		}
+		fmt.Printf("Agent '%s' deleted\n", name)
+	}
+	return nil
+}
+
+func runSuspendAgent(cmd *cobra.Command, args []string) error {
+	return setAgentsSuspended(args, true)
+}
+
+func runResumeAgent(cmd *cobra.Command, args []string) error {
+	return setAgentsSuspended(args, false)
+}
+
+// setAgentsSuspended suspends or resumes agents by name
+func setAgentsSuspended(names []string, suspended bool) error {
+	client, err := newKubeClient(agentKubeOpts)
+	if err != nil {
+		return err
+	}
+
+	action := "resume"
+	if suspended {
+		action = "suspend"
+	}
+	for _, name := range names {
+		if err := client.setAgentSuspended(context.Background(), client.namespace, name, suspended); err != nil {
+			return fmt.Errorf("failed to %s agent '%s': %v", action, name, err)
+		}
+		fmt.Printf("Agent '%s' %sd\n", name, action)
+	}
+	return nil
+}
+
+func runInvokeAgent(cmd *cobra.Command, args []string) error {
+	messageStr, _, err := readJSONInput(agentInvokeJSON)
+	if err != nil {
+		return err
+	}
+
+	properties, err := parseRPCProperties(agentInvokeProperties)
+	if err != nil {
+		return err
+	}
+
+	kube, err := newKubeClient(agentKubeOpts)
+	if err != nil {
+		return err
+	}
+
+	a, err := kube.getAgent(context.Background(), kube.namespace, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get agent '%s': %v", args[0], err)
+	}
+
+	requestTopic := sourceTopic(a.Spec.RequestSource)
+	if requestTopic == "" {
+		return fmt.Errorf("agent '%s' has no request topic (spec.requestSource.pulsar.topic)", a.Name)
+	}
+	responseTopic := agentInvokeResponseTopic
+	if responseTopic == "" {
+		responseTopic = sourceTopic(a.Spec.ResponseSource)
+	}
+	if responseTopic == "" {
+		return fmt.Errorf("agent '%s' has no response topic yet, wait for the operator to reconcile it or use --response-topic", a.Name)
+	}
+
+	// Get configuration from context or command line
+	cfg, err := resolveConfig(configFlags{pulsarURL: agentInvokePulsarURL, auth: agentInvokeAuth})
+	if err != nil {
+		return fmt.Errorf("failed to get context configuration: %v", err)
+	}
+
+	// Create client
+	clientOpts, err := buildClientOptions(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build client options: %v", err)
+	}
+
+	client, err := pulsar.NewClient(clientOpts)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	msg, err := callRPC(client, rpcRequest{
+		topic:         requestTopic,
+		responseTopic: responseTopic,
+		payload:       []byte(messageStr),
+		key:           agentInvokeKey,
+		orderingKey:   agentInvokeOrderKey,
+		properties:    properties,
+	}, agentInvokeTimeout)
+	if err != nil {
+		return err
+	}
+
+	printRPCResponse(msg)
+	return nil
+}
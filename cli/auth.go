@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	pulsarauth "github.com/apache/pulsar-client-go/pulsar/auth"
+	"github.com/apache/pulsar-client-go/pulsaradmin"
+	"github.com/spf13/cobra"
+)
+
+const envToken = "ASCLI_TOKEN"
+
+// authFlags holds the authentication and TLS flags shared by the commands that
+// connect to Pulsar. The shortcuts spare users the plugin class names and JSON
+// parameters of --auth-plugin and --auth-params
+type authFlags struct {
+	authPlugin         string
+	authParams         string
+	token              string
+	tokenFile          string
+	tlsCert            string
+	tlsKey             string
+	tlsCA              string
+	tlsAllowInsecure   optionalBool
+	oauth2IssuerURL    string
+	oauth2Audience     string
+	oauth2Scope        string
+	oauth2ClientID     string
+	oauth2ClientSecret string
+	oauth2KeyFile      string
+}
+
+// optionalBool is a boolean flag that tells whether it was given, so that false can
+// override a context that sets true
+type optionalBool struct {
+	value bool
+	set   bool
+}
+
+func (b *optionalBool) String() string {
+	return strconv.FormatBool(b.value)
+}
+
+func (b *optionalBool) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	b.value, b.set = v, true
+	return nil
+}
+
+func (b *optionalBool) Type() string {
+	return "bool"
+}
+
+// setting returns the flag as a setting value, empty if it was not given
+func (b *optionalBool) setting() string {
+	if !b.set {
+		return ""
+	}
+	return strconv.FormatBool(b.value)
+}
+
+// addAuthFlags registers the authentication and TLS flags on a command
+func addAuthFlags(cmd *cobra.Command, f *authFlags) {
+	flags := cmd.Flags()
+	flags.StringVar(&f.authPlugin, "auth-plugin", "", "Authentication plugin class name (overrides context)")
+	flags.StringVar(&f.authParams, "auth-params", "", "Authentication parameters (JSON string) (overrides context)")
+	flags.StringVar(&f.token, "token", "", "JWT token to authenticate with (overrides context)")
+	flags.StringVar(&f.tokenFile, "token-file", "", "File containing the JWT token to authenticate with (overrides context)")
+	flags.StringVar(&f.tlsCert, "tls-cert", "", "Client certificate file for TLS authentication (overrides context)")
+	flags.StringVar(&f.tlsKey, "tls-key", "", "Client private key file for TLS authentication (overrides context)")
+	flags.StringVar(&f.tlsCA, "tls-ca", "", "CA certificates file used to verify the server (overrides context)")
+	flags.VarPF(&f.tlsAllowInsecure, "tls-allow-insecure", "", "Accept untrusted TLS certificates from the server (overrides context)").NoOptDefVal = "true"
+	flags.StringVar(&f.oauth2IssuerURL, "oauth2-issuer-url", "", "OAuth2 issuer URL for the client credentials flow (overrides context)")
+	flags.StringVar(&f.oauth2Audience, "oauth2-audience", "", "OAuth2 audience")
+	flags.StringVar(&f.oauth2Scope, "oauth2-scope", "", "OAuth2 scope")
+	flags.StringVar(&f.oauth2ClientID, "oauth2-client-id", "", "OAuth2 client ID")
+	flags.StringVar(&f.oauth2ClientSecret, "oauth2-client-secret", "", "OAuth2 client secret")
+	flags.StringVar(&f.oauth2KeyFile, "oauth2-key-file", "", "OAuth2 credentials file with client_id and client_secret, instead of --oauth2-client-id and --oauth2-client-secret")
+}
+
+// authConfig is one way of authenticating with Pulsar. At most one method may be
+// configured
+type authConfig struct {
+	plugin             string
+	params             string
+	token              string
+	tokenFile          string
+	tlsCert            string
+	tlsKey             string
+	oauth2IssuerURL    string
+	oauth2Audience     string
+	oauth2Scope        string
+	oauth2ClientID     string
+	oauth2ClientSecret string
+	oauth2KeyFile      string
+}
+
+// authFromFlags returns the authentication given on the command line
+func authFromFlags(f authFlags) authConfig {
+	return authConfig{
+		plugin:             f.authPlugin,
+		params:             f.authParams,
+		token:              f.token,
+		tokenFile:          f.tokenFile,
+		tlsCert:            f.tlsCert,
+		tlsKey:             f.tlsKey,
+		oauth2IssuerURL:    f.oauth2IssuerURL,
+		oauth2Audience:     f.oauth2Audience,
+		oauth2Scope:        f.oauth2Scope,
+		oauth2ClientID:     f.oauth2ClientID,
+		oauth2ClientSecret: f.oauth2ClientSecret,
+		oauth2KeyFile:      f.oauth2KeyFile,
+	}
+}
+
+// authFromContext returns the authentication stored in a context
+func authFromContext(ctx Context) authConfig {
+	return authConfig{
+		plugin:             ctx.AuthPlugin,
+		params:             ctx.AuthParams,
+		token:              ctx.Token,
+		tokenFile:          ctx.TokenFile,
+		tlsCert:            ctx.TLSCertFile,
+		tlsKey:             ctx.TLSKeyFile,
+		oauth2IssuerURL:    ctx.OAuth2IssuerURL,
+		oauth2Audience:     ctx.OAuth2Audience,
+		oauth2Scope:        ctx.OAuth2Scope,
+		oauth2ClientID:     ctx.OAuth2ClientID,
+		oauth2ClientSecret: ctx.OAuth2ClientSecret,
+		oauth2KeyFile:      ctx.OAuth2KeyFile,
+	}
+}
+
+func (a authConfig) isEmpty() bool {
+	return a == authConfig{}
+}
+
+// method returns the configured authentication method: none, plugin, token, tls or
+// oauth2. Settings of more than one method, or an incomplete method, are an error
+func (a authConfig) method() (string, error) {
+	var methods []string
+	if a.plugin != "" || a.params != "" {
+		methods = append(methods, "plugin")
+	}
+	if a.token != "" || a.tokenFile != "" {
+		methods = append(methods, "token")
+	}
+	if a.tlsCert != "" || a.tlsKey != "" {
+		methods = append(methods, "tls")
+	}
+	if a.oauth2IssuerURL != "" || a.oauth2Audience != "" || a.oauth2Scope != "" ||
+		a.oauth2ClientID != "" || a.oauth2ClientSecret != "" || a.oauth2KeyFile != "" {
+		methods = append(methods, "oauth2")
+	}
+	if len(methods) == 0 {
+		return "none", nil
+	}
+	if len(methods) > 1 {
+		return "", fmt.Errorf("conflicting authentication settings for %s, use only one method", strings.Join(methods, " and "))
+	}
+
+	switch methods[0] {
+	case "plugin":
+		if a.plugin == "" {
+			return "", fmt.Errorf("--auth-params requires --auth-plugin")
+		}
+	case "token":
+		if a.token != "" && a.tokenFile != "" {
+			return "", fmt.Errorf("--token and --token-file cannot be used together")
+		}
+	case "tls":
+		if a.tlsCert == "" || a.tlsKey == "" {
+			return "", fmt.Errorf("TLS authentication requires both --tls-cert and --tls-key")
+		}
+	case "oauth2":
+		if a.oauth2IssuerURL == "" {
+			return "", fmt.Errorf("OAuth2 authentication requires --oauth2-issuer-url")
+		}
+		if a.oauth2KeyFile != "" && (a.oauth2ClientID != "" || a.oauth2ClientSecret != "") {
+			return "", fmt.Errorf("--oauth2-key-file cannot be used with --oauth2-client-id or --oauth2-client-secret")
+		}
+		if a.oauth2KeyFile == "" && (a.oauth2ClientID == "" || a.oauth2ClientSecret == "") {
+			return "", fmt.Errorf("OAuth2 authentication requires --oauth2-client-id and --oauth2-client-secret, or --oauth2-key-file")
+		}
+	}
+	return methods[0], nil
+}
+
+// oauth2KeyFileURL returns the credentials for the client credentials flow, inlining
+// the client ID and secret as a data:// key file when no key file is given
+func (a authConfig) oauth2KeyFileURL() (string, error) {
+	if a.oauth2KeyFile != "" {
+		return a.oauth2KeyFile, nil
+	}
+	data, err := json.Marshal(map[string]string{
+		"type":          "client_credentials",
+		"client_id":     a.oauth2ClientID,
+		"client_secret": a.oauth2ClientSecret,
+		"issuer_url":    a.oauth2IssuerURL,
+	})
+	if err != nil {
+		return "", err
+	}
+	return "data://" + string(data), nil
+}
+
+// authentication creates the Pulsar client authentication, or nil if none is configured
+func (a authConfig) authentication() (pulsar.Authentication, error) {
+	method, err := a.method()
+	if err != nil {
+		return nil, err
+	}
+
+	switch method {
+	case "plugin":
+		auth, err := pulsar.NewAuthentication(a.plugin, a.params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create authentication: %v", err)
+		}
+		return auth, nil
+	case "token":
+		if a.tokenFile != "" {
+			return pulsar.NewAuthenticationTokenFromFile(a.tokenFile), nil
+		}
+		return pulsar.NewAuthenticationToken(a.token), nil
+	case "tls":
+		return pulsar.NewAuthenticationTLS(a.tlsCert, a.tlsKey), nil
+	case "oauth2":
+		keyFile, err := a.oauth2KeyFileURL()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create authentication: %v", err)
+		}
+		// The grant is requested here, so bad credentials are reported up front
+		auth, err := pulsarauth.NewAuthenticationOAuth2WithParams(map[string]string{
+			pulsarauth.ConfigParamType:      pulsarauth.ConfigParamTypeClientCredentials,
+			pulsarauth.ConfigParamIssuerURL: a.oauth2IssuerURL,
+			pulsarauth.ConfigParamAudience:  a.oauth2Audience,
+			pulsarauth.ConfigParamScope:     a.oauth2Scope,
+			pulsarauth.ConfigParamClientID:  a.oauth2ClientID,
+			pulsarauth.ConfigParamKeyFile:   keyFile,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain an OAuth2 token from %s: %v", a.oauth2IssuerURL, err)
+		}
+		return auth, nil
+	}
+	return nil, nil
+}
+
+// applyAdmin sets the authentication of an admin client configuration
+func (a authConfig) applyAdmin(cfg *pulsaradmin.Config) error {
+	method, err := a.method()
+	if err != nil {
+		return err
+	}
+
+	switch method {
+	case "plugin":
+		cfg.AuthPlugin = a.plugin
+		cfg.AuthParams = a.params
+	case "token":
+		cfg.Token = a.token
+		cfg.TokenFile = a.tokenFile
+	case "tls":
+		cfg.TLSCertFile = a.tlsCert
+		cfg.TLSKeyFile = a.tlsKey
+	case "oauth2":
+		keyFile, err := a.oauth2KeyFileURL()
+		if err != nil {
+			return err
+		}
+		cfg.AuthPlugin = "oauth2"
+		cfg.IssuerEndpoint = a.oauth2IssuerURL
+		cfg.Audience = a.oauth2Audience
+		cfg.Scope = a.oauth2Scope
+		cfg.ClientID = a.oauth2ClientID
+		cfg.KeyFile = keyFile
+	}
+	return nil
+}
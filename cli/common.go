@@ -2,59 +2,74 @@ package main
 
 import (
 	"fmt"
+	neturl "net/url"
+	"strings"
 
 	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/apache/pulsar-client-go/pulsaradmin"
 )
 
-// buildClientOptions creates Pulsar client options with optional authentication
-func buildClientOptions(url string, authPlugin, authParams string) (pulsar.ClientOptions, error) {
+// buildClientOptions creates Pulsar client options from the resolved configuration
+func buildClientOptions(cfg *resolvedConfig) (pulsar.ClientOptions, error) {
 	opts := pulsar.ClientOptions{
-		URL: url,
+		URL:                        cfg.pulsarURL.value,
+		TLSTrustCertsFilePath:      cfg.tlsTrustCertsFile.value,
+		TLSAllowInsecureConnection: cfg.tlsAllowInsecure.value == "true",
+		TLSValidateHostname:        cfg.tlsValidateHostname.value == "true",
 	}
 
-	if authPlugin != "" {
-		auth, err := pulsar.NewAuthentication(authPlugin, authParams)
-		if err != nil {
-			return opts, fmt.Errorf("failed to create authentication: %v", err)
-		}
+	auth, err := cfg.auth.authentication()
+	if err != nil {
+		return opts, err
+	}
+	if auth != nil {
 		opts.Authentication = auth
 	}
 
 	return opts, nil
 }
 
-// getContextConfig gets Pulsar configuration from current context, with fallback to provided values
-func getContextConfig(providedURL, providedAuthPlugin, providedAuthParams string) (string, string, string, error) {
-	// If all parameters are provided via command line, use them
-	if providedURL != "" && providedAuthPlugin != "" && providedAuthParams != "" {
-		return providedURL, providedAuthPlugin, providedAuthParams, nil
-	}
-
-	// Try to get configuration from current context
-	currentCtx, err := getCurrentContext()
-	if err != nil {
-		// If no context is set, use provided values with defaults
-		if providedURL == "" {
-			providedURL = "pulsar://localhost:6650"
+// parseProperties parses repeated key=value flags into a message properties map
+func parseProperties(pairs []string) (map[string]string, error) {
+	properties := make(map[string]string)
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid property %q, expected key=value", pair)
 		}
-		return providedURL, providedAuthPlugin, providedAuthParams, nil
+		properties[key] = value
 	}
+	return properties, nil
+}
 
-	// Use context values, with fallback to provided values
-	url := currentCtx.PulsarURL
-	if providedURL != "" {
-		url = providedURL
+// defaultAdminURL derives the admin web service URL from a Pulsar service URL,
+// assuming the broker listens on the default HTTP ports
+func defaultAdminURL(serviceURL string) string {
+	u, err := neturl.Parse(serviceURL)
+	if err != nil || u.Hostname() == "" {
+		return "http://localhost:8080"
 	}
-
-	authPlugin := currentCtx.AuthPlugin
-	if providedAuthPlugin != "" {
-		authPlugin = providedAuthPlugin
+	if u.Scheme == "pulsar+ssl" {
+		return fmt.Sprintf("https://%s:8443", u.Hostname())
 	}
+	return fmt.Sprintf("http://%s:8080", u.Hostname())
+}
 
-	authParams := currentCtx.AuthParams
-	if providedAuthParams != "" {
-		authParams = providedAuthParams
+// buildAdminClient creates a Pulsar admin client for the resolved admin URL
+func buildAdminClient(cfg *resolvedConfig) (pulsaradmin.Client, error) {
+	adminCfg := &pulsaradmin.Config{
+		WebServiceURL:                 cfg.adminURL.value,
+		TLSTrustCertsFilePath:         cfg.tlsTrustCertsFile.value,
+		TLSAllowInsecureConnection:    cfg.tlsAllowInsecure.value == "true",
+		TLSEnableHostnameVerification: cfg.tlsValidateHostname.value == "true",
+	}
+	if err := cfg.auth.applyAdmin(adminCfg); err != nil {
+		return nil, err
 	}
 
-	return url, authPlugin, authParams, nil
+	client, err := pulsaradmin.NewClient(adminCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin client: %v", err)
+	}
+	return client, nil
 }
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds the cluster lookups done while completing, so a slow or
+// unreachable cluster does not hang the shell
+const completionTimeout = 5 * time.Second
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish]",
+	Short: "Generate the shell completion script",
+	Long: `Generate the completion script for bash, zsh or fish.
+
+Besides commands and flags, context names, topics and agent names are completed
+from the contexts file, the Pulsar admin API and the Kubernetes cluster.
+
+Bash (requires the bash-completion package):
+  source <(ascli completion bash)
+  # Load for every session
+  ascli completion bash > /etc/bash_completion.d/ascli
+
+Zsh:
+  # Enable completion once if it is not enabled yet
+  echo "autoload -U compinit; compinit" >> ~/.zshrc
+  ascli completion zsh > "${fpath[1]}/_ascli"
+
+Fish:
+  ascli completion fish > ~/.config/fish/completions/ascli.fish`,
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs:             []string{"bash", "zsh", "fish"},
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+	// The completion command above replaces the one cobra adds by default
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(os.Stdout)
+	default:
+		return rootCmd.GenFishCompletion(os.Stdout, true)
+	}
+}
+
+// completeContextNames completes the names of the contexts in the contexts file
+func completeContextNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if err := loadContextConfig(); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for name := range contextConfig.Contexts {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeContextArg completes a single context name argument
+func completeContextArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeContextNames(cmd, args, toComplete)
+}
+
+// registerTopicCompletion completes the --topic flag of a command with the topics
+// listed by the admin API of the cluster the command would connect to
+func registerTopicCompletion(cmd *cobra.Command, pulsarURL *string, auth *authFlags) {
+	cmd.RegisterFlagCompletionFunc("topic", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		adminURL, _ := cmd.Flags().GetString("admin-url")
+		cfg, err := resolveConfig(configFlags{pulsarURL: *pulsarURL, adminURL: adminURL, auth: *auth})
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeTopics(cfg, toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// completeTopicArg returns a completion function for a leading topic argument
+func completeTopicArg(flags *configFlags) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		cfg, err := resolveConfig(*flags)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeTopics(cfg, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeTopics lists the topics matching toComplete. Once a tenant and namespace
+// are typed only that namespace is listed, otherwise every namespace of every tenant
+func completeTopics(cfg *resolvedConfig, toComplete string) []string {
+	admin, err := buildAdminClient(cfg)
+	if err != nil {
+		return nil
+	}
+
+	var namespaces []string
+	name := toComplete
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+3:]
+	}
+	if parts := strings.Split(name, "/"); len(parts) >= 3 {
+		namespaces = []string{parts[0] + "/" + parts[1]}
+	} else {
+		tenants, err := admin.Tenants().List()
+		if err != nil {
+			return nil
+		}
+		for _, tenant := range tenants {
+			names, err := admin.Namespaces().GetNamespaces(tenant)
+			if err != nil {
+				continue
+			}
+			namespaces = append(namespaces, names...)
+		}
+	}
+
+	deadline := time.Now().Add(completionTimeout)
+	var topics []string
+	for _, namespace := range namespaces {
+		if time.Now().After(deadline) {
+			break
+		}
+		ns, err := utils.GetNamespaceName(namespace)
+		if err != nil {
+			continue
+		}
+		partitioned, nonPartitioned, err := admin.Topics().List(*ns)
+		if err != nil {
+			continue
+		}
+		for _, topic := range append(partitioned, nonPartitioned...) {
+			// Partitions are read through their partitioned topic
+			if strings.Contains(topic, "-partition-") {
+				continue
+			}
+			// Names are offered in the form being typed, and topics in public/default by
+			// their short name, like they are usually given
+			candidate := topic
+			if !strings.Contains(toComplete, "://") {
+				candidate = strings.TrimPrefix(candidate, "persistent://")
+				if !strings.Contains(toComplete, "/") {
+					candidate = strings.TrimPrefix(candidate, "public/default/")
+				}
+			}
+			if strings.HasPrefix(candidate, toComplete) {
+				topics = append(topics, candidate)
+			}
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// completeAgentNames returns a completion function for agent name arguments. At most
+// maxArgs names are completed, or any number if maxArgs is negative
+func completeAgentNames(opts *kubeOptions, maxArgs int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if maxArgs >= 0 && len(args) >= maxArgs {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		client, err := newKubeClient(*opts)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+		agents, err := client.listAgents(ctx, client.namespace)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var names []string
+		for _, a := range agents {
+			if strings.HasPrefix(a.Name, toComplete) {
+				names = append(names, a.Name)
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// Settings are resolved in this order: command-line flag, environment variable,
+// selected context, default. Each setting is resolved on its own, so a flag can
+// override the URL of a context while its authentication still applies.
+// Authentication is resolved as a whole, see resolveConfig.
+const (
+	envPulsarURL   = "ASCLI_PULSAR_URL"
+	envAdminURL    = "ASCLI_ADMIN_URL"
+	envAuthPlugin  = "ASCLI_AUTH_PLUGIN"
+	envAuthParams  = "ASCLI_AUTH_PARAMS"
+	envKubeContext = "ASCLI_KUBE_CONTEXT"
+	envNamespace   = "ASCLI_NAMESPACE"
+
+	defaultPulsarURL = "pulsar://localhost:6650"
+)
+
+// setting is a resolved configuration value and where it came from
+type setting struct {
+	value  string
+	source string
+}
+
+// configFlags holds the command-line values that take precedence over everything else
+type configFlags struct {
+	pulsarURL   string
+	adminURL    string
+	auth        authFlags
+	kubeContext string
+	namespace   string
+}
+
+// resolvedConfig is the effective configuration of an invocation
+type resolvedConfig struct {
+	context             *Context
+	contextSource       string
+	pulsarURL           setting
+	adminURL            setting
+	auth                authConfig
+	authSource          string
+	tlsTrustCertsFile   setting
+	tlsAllowInsecure    setting
+	tlsValidateHostname setting
+	kubeContext         setting
+	namespace           setting
+}
+
+var (
+	configViewResolved bool
+	configViewFlags    configFlags
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the CLI configuration",
+}
+
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Show the contexts file or the resolved configuration",
+	Long: `Show the contexts file, or with --resolved the effective configuration and
+where each value comes from.
+
+Each setting is resolved in this order:
+  1. Command-line flag (e.g. --pulsar-url)
+  2. Environment variable (ASCLI_PULSAR_URL, ASCLI_ADMIN_URL, ASCLI_AUTH_PLUGIN,
+     ASCLI_AUTH_PARAMS, ASCLI_TOKEN, ASCLI_KUBE_CONTEXT, ASCLI_NAMESPACE)
+  3. The selected context (--context, then ASCLI_CONTEXT, then the current context)
+  4. Default
+
+Authentication is taken as a whole from the first of these that sets any of it, so
+--token on the command line replaces the OAuth2 settings of a context rather than
+mixing with them. Secrets are never printed.
+
+Examples:
+  ascli config view
+  ascli config view --resolved
+  ascli config view --resolved --context prod --pulsar-url pulsar://other:6650`,
+	Args: cobra.NoArgs,
+	RunE: runConfigView,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configViewCmd)
+
+	configViewCmd.Flags().BoolVar(&configViewResolved, "resolved", false, "Show the effective configuration and the source of each value")
+	configViewCmd.Flags().StringVar(&configViewFlags.pulsarURL, "pulsar-url", "", "Service URL, to preview its effect")
+	configViewCmd.Flags().StringVar(&configViewFlags.adminURL, "admin-url", "", "Admin service URL, to preview its effect")
+	addAuthFlags(configViewCmd, &configViewFlags.auth)
+	configViewCmd.Flags().StringVar(&configViewFlags.kubeContext, "kube-context", "", "Kubeconfig context, to preview its effect")
+	configViewCmd.Flags().StringVarP(&configViewFlags.namespace, "namespace", "n", "", "Kubernetes namespace, to preview its effect")
+}
+
+// resolveSetting picks the first value that is set from the flag, the environment
+// variable, the context and the default
+func resolveSetting(flagName, flagValue, envName string, ctx *Context, contextValue, defaultValue string) setting {
+	if flagValue != "" {
+		return setting{value: flagValue, source: "flag --" + flagName}
+	}
+	if envName != "" {
+		if value := os.Getenv(envName); value != "" {
+			return setting{value: value, source: "env " + envName}
+		}
+	}
+	if ctx != nil && contextValue != "" {
+		return setting{value: contextValue, source: fmt.Sprintf("context '%s'", ctx.Name)}
+	}
+	return setting{value: defaultValue, source: "default"}
+}
+
+// resolveConfig resolves the effective configuration. A contexts file that cannot be
+// parsed or a selected context that does not exist is an error
+func resolveConfig(flags configFlags) (*resolvedConfig, error) {
+	ctx, err := getCurrentContext()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &resolvedConfig{context: ctx}
+	var values Context
+	if ctx != nil {
+		values = *ctx
+		_, cfg.contextSource = activeContextSource()
+	}
+
+	cfg.pulsarURL = resolveSetting("pulsar-url", flags.pulsarURL, envPulsarURL, ctx, values.PulsarURL, defaultPulsarURL)
+	cfg.adminURL = resolveSetting("admin-url", flags.adminURL, envAdminURL, ctx, values.AdminURL, "")
+	if cfg.adminURL.value == "" {
+		cfg.adminURL = setting{value: defaultAdminURL(cfg.pulsarURL.value), source: "default (derived from pulsar-url)"}
+	}
+	cfg.tlsTrustCertsFile = resolveSetting("tls-ca", flags.auth.tlsCA, "", ctx, values.TLSTrustCertsFile, "")
+	cfg.tlsAllowInsecure = resolveSetting("tls-allow-insecure", flags.auth.tlsAllowInsecure.setting(), "", ctx, boolSetting(values.TLSAllowInsecure), "false")
+	cfg.tlsValidateHostname = resolveSetting("", "", "", ctx, boolSetting(values.TLSValidateHostname), "false")
+	cfg.kubeContext = resolveSetting("kube-context", flags.kubeContext, envKubeContext, ctx, values.KubeContext, "")
+	cfg.namespace = resolveSetting("namespace", flags.namespace, envNamespace, ctx, values.Namespace, "")
+
+	// Authentication is taken as a whole from the first layer that sets any of it, so
+	// a token flag does not mix with the OAuth2 settings of a context
+	cfg.auth, cfg.authSource = authFromFlags(flags.auth), "flags"
+	if cfg.auth.isEmpty() {
+		cfg.auth = authConfig{
+			plugin: os.Getenv(envAuthPlugin),
+			params: os.Getenv(envAuthParams),
+			token:  os.Getenv(envToken),
+		}
+		cfg.authSource = "env"
+	}
+	if cfg.auth.isEmpty() && ctx != nil {
+		cfg.auth, cfg.authSource = authFromContext(values), fmt.Sprintf("context '%s'", ctx.Name)
+	}
+	if cfg.auth.isEmpty() {
+		cfg.authSource = "default"
+	}
+	if _, err := cfg.auth.method(); err != nil {
+		return nil, fmt.Errorf("invalid authentication settings from %s: %v", cfg.authSource, err)
+	}
+	return cfg, nil
+}
+
+// boolSetting formats a context boolean, leaving false unset so the default applies
+func boolSetting(b bool) string {
+	if !b {
+		return ""
+	}
+	return strconv.FormatBool(b)
+}
+
+func runConfigView(cmd *cobra.Command, args []string) error {
+	if err := loadContextConfig(); err != nil {
+		return err
+	}
+
+	if !configViewResolved {
+		fmt.Printf("# %s\n", contextConfigPath)
+		// Contexts written before secrets were moved out may still hold auth params
+		view := ContextConfig{CurrentContext: contextConfig.CurrentContext, Contexts: make(map[string]Context)}
+		for name, ctx := range contextConfig.Contexts {
+			if ctx.AuthParams != "" {
+				ctx.AuthParams = "<redacted>"
+			}
+			if ctx.Token != "" {
+				ctx.Token = "<redacted>"
+			}
+			if ctx.OAuth2ClientSecret != "" {
+				ctx.OAuth2ClientSecret = "<redacted>"
+			}
+			view.Contexts[name] = ctx
+		}
+		data, err := yaml.Marshal(view)
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %v", err)
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+
+	cfg, err := resolveConfig(configViewFlags)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Config file: %s\n", contextConfigPath)
+	if cfg.context != nil {
+		fmt.Printf("Context: %s (selected by %s)\n", cfg.context.Name, cfg.contextSource)
+	} else {
+		fmt.Println("Context: <none>")
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+	type row struct {
+		name    string
+		setting setting
+		secret  bool
+		unset   string
+	}
+	rows := []row{
+		{"pulsar-url", cfg.pulsarURL, false, ""},
+		{"admin-url", cfg.adminURL, false, ""},
+	}
+	method, _ := cfg.auth.method()
+	rows = append(rows, row{"auth-method", setting{method, cfg.authSource}, false, ""})
+	// Only the settings of the authentication method in use are shown
+	for _, auth := range []struct {
+		name   string
+		value  string
+		secret bool
+	}{
+		{"auth-plugin", cfg.auth.plugin, false},
+		{"auth-params", cfg.auth.params, true},
+		{"token", cfg.auth.token, true},
+		{"token-file", cfg.auth.tokenFile, false},
+		{"tls-cert", cfg.auth.tlsCert, false},
+		{"tls-key", cfg.auth.tlsKey, false},
+		{"oauth2-issuer-url", cfg.auth.oauth2IssuerURL, false},
+		{"oauth2-audience", cfg.auth.oauth2Audience, false},
+		{"oauth2-scope", cfg.auth.oauth2Scope, false},
+		{"oauth2-client-id", cfg.auth.oauth2ClientID, false},
+		{"oauth2-client-secret", cfg.auth.oauth2ClientSecret, true},
+		{"oauth2-key-file", cfg.auth.oauth2KeyFile, false},
+	} {
+		if auth.value != "" {
+			rows = append(rows, row{auth.name, setting{auth.value, cfg.authSource}, auth.secret, ""})
+		}
+	}
+	rows = append(rows, []row{
+		{"tls-ca", cfg.tlsTrustCertsFile, false, "<none>"},
+		{"tls-allow-insecure", cfg.tlsAllowInsecure, false, ""},
+		{"tls-validate-hostname", cfg.tlsValidateHostname, false, ""},
+		{"kube-context", cfg.kubeContext, false, "<kubeconfig current context>"},
+		{"namespace", cfg.namespace, false, "<kube context namespace>"},
+	}...)
+	for _, row := range rows {
+		value := row.setting.value
+		switch {
+		case value == "":
+			value = row.unset
+		case row.secret:
+			value = "<redacted>"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", row.name, value, row.setting.source)
+	}
+	return w.Flush()
+}
@@ -5,19 +5,58 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
 )
 
 // Context represents an AgentStream connection context
 type Context struct {
-	Name        string `json:"name"`
-	PulsarURL   string `json:"pulsar_url"`
-	AuthPlugin  string `json:"auth_plugin,omitempty"`
-	AuthParams  string `json:"auth_params,omitempty"`
+	Name       string `json:"name"`
+	PulsarURL  string `json:"pulsar_url"`
+	AdminURL   string `json:"admin_url,omitempty"`
+	AuthPlugin string `json:"auth_plugin,omitempty"`
+	// AuthParams is kept in the secret store and is only present in contexts files
+	// written before secrets were moved out. Such contexts are migrated on save
+	AuthParams string `json:"auth_params,omitempty"`
+	// Token and OAuth2ClientSecret are kept in the secret store like AuthParams
+	Token               string `json:"token,omitempty"`
+	TokenFile           string `json:"token_file,omitempty"`
+	TLSCertFile         string `json:"tls_cert_file,omitempty"`
+	TLSKeyFile          string `json:"tls_key_file,omitempty"`
+	OAuth2IssuerURL     string `json:"oauth2_issuer_url,omitempty"`
+	OAuth2Audience      string `json:"oauth2_audience,omitempty"`
+	OAuth2Scope         string `json:"oauth2_scope,omitempty"`
+	OAuth2ClientID      string `json:"oauth2_client_id,omitempty"`
+	OAuth2ClientSecret  string `json:"oauth2_client_secret,omitempty"`
+	OAuth2KeyFile       string `json:"oauth2_key_file,omitempty"`
+	TLSTrustCertsFile   string `json:"tls_trust_certs_file,omitempty"`
+	TLSAllowInsecure    bool   `json:"tls_allow_insecure,omitempty"`
+	TLSValidateHostname bool   `json:"tls_validate_hostname,omitempty"`
+	KubeContext         string `json:"kube_context,omitempty"`
+	Namespace           string `json:"namespace,omitempty"`
+	// SecretStore is where the secrets of the context are kept: keyring or file
+	SecretStore string `json:"secret_store,omitempty"`
 	Description string `json:"description,omitempty"`
 }
 
+// secrets returns the sensitive settings of the context
+func (c *Context) secrets() contextSecrets {
+	return contextSecrets{
+		AuthParams:         c.AuthParams,
+		Token:              c.Token,
+		OAuth2ClientSecret: c.OAuth2ClientSecret,
+	}
+}
+
+// setSecrets restores the sensitive settings of the context
+func (c *Context) setSecrets(secrets contextSecrets) {
+	c.AuthParams = secrets.AuthParams
+	c.Token = secrets.Token
+	c.OAuth2ClientSecret = secrets.OAuth2ClientSecret
+}
+
 // ContextConfig represents the configuration file structure
 type ContextConfig struct {
 	CurrentContext string             `json:"current_context"`
@@ -27,6 +66,7 @@ type ContextConfig struct {
 var (
 	contextConfigPath string
 	contextConfig     *ContextConfig
+	contextOverride   string
 )
 
 var contextCmd = &cobra.Command{
@@ -36,10 +76,18 @@ var contextCmd = &cobra.Command{
 	
 Examples:
   ascli context create my-context --pulsar-url pulsar://localhost:6650
-  ascli context create prod-context --pulsar-url pulsar://prod-server:6650 --auth-plugin org.apache.pulsar.client.impl.auth.AuthenticationTls --auth-params '{"tlsCertFile": "/path/to/cert.pem"}'
+  ascli context create prod-context --pulsar-url pulsar+ssl://prod-server:6651 --tls-cert /path/to/cert.pem --tls-key /path/to/key.pem
+  ascli context create staging --pulsar-url pulsar+ssl://staging:6651 --tls-ca /path/to/ca.pem --token-file /path/to/token --kube-context staging --namespace agents
+  ascli context create cloud --pulsar-url pulsar+ssl://cloud:6651 --oauth2-issuer-url https://auth.example.com --oauth2-audience urn:pulsar:cloud --oauth2-client-id my-app --oauth2-client-secret ...
   ascli context use my-context
   ascli context list
-  ascli context delete my-context`,
+  ascli context update my-context --namespace agents
+  ascli context rename my-context local
+  ascli context export --redact > contexts.yaml
+  ascli context import contexts.yaml
+  ascli context delete my-context
+  ascli --context prod agent list        # Use a context for a single command
+  ASCLI_CONTEXT=prod ascli agent list`,
 }
 
 func init() {
@@ -49,6 +97,13 @@ func init() {
 	contextCmd.AddCommand(listContextCmd)
 	contextCmd.AddCommand(deleteContextCmd)
 	contextCmd.AddCommand(getCurrentContextCmd)
+	contextCmd.AddCommand(updateContextCmd)
+	contextCmd.AddCommand(renameContextCmd)
+	contextCmd.AddCommand(exportContextCmd)
+	contextCmd.AddCommand(importContextCmd)
+
+	rootCmd.PersistentFlags().StringVar(&contextOverride, "context", "", "Context to use for this command (overrides ASCLI_CONTEXT and the current context)")
+	rootCmd.RegisterFlagCompletionFunc("context", completeContextNames)
 }
 
 var createContextCmd = &cobra.Command{
@@ -59,10 +114,11 @@ var createContextCmd = &cobra.Command{
 }
 
 var useContextCmd = &cobra.Command{
-	Use:   "use [name]",
-	Short: "Switch to a context",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runUseContext,
+	Use:               "use [name]",
+	Short:             "Switch to a context",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeContextArg,
+	RunE:              runUseContext,
 }
 
 var listContextCmd = &cobra.Command{
@@ -72,10 +128,11 @@ var listContextCmd = &cobra.Command{
 }
 
 var deleteContextCmd = &cobra.Command{
-	Use:   "delete [name]",
-	Short: "Delete a context",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runDeleteContext,
+	Use:               "delete [name]",
+	Short:             "Delete a context",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeContextArg,
+	RunE:              runDeleteContext,
 }
 
 var getCurrentContextCmd = &cobra.Command{
@@ -84,13 +141,137 @@ var getCurrentContextCmd = &cobra.Command{
 	RunE:  runGetCurrentContext,
 }
 
-func init() {
-	createContextCmd.Flags().String("pulsar-url", "", "Pulsar service URL (required)")
-	createContextCmd.Flags().String("auth-plugin", "", "Authentication plugin class name")
-	createContextCmd.Flags().String("auth-params", "", "Authentication parameters (JSON string)")
-	createContextCmd.Flags().String("description", "", "Context description")
+var updateContextCmd = &cobra.Command{
+	Use:   "update [name]",
+	Short: "Update the settings of a context",
+	Long: `Update the settings of a context. Only the flags that are given are changed,
+and a flag set to an empty value clears the setting.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeContextArg,
+	RunE:              runUpdateContext,
+}
 
+var renameContextCmd = &cobra.Command{
+	Use:               "rename [old-name] [new-name]",
+	Short:             "Rename a context",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeContextArg,
+	RunE:              runRenameContext,
+}
+
+var exportContextCmd = &cobra.Command{
+	Use:   "export [name...]",
+	Short: "Export contexts as YAML",
+	Long: `Export contexts as YAML, to share them or move them to another machine.
+All contexts are exported if no names are given. Secrets are included unless
+--redact is set.`,
+	ValidArgsFunction: completeContextNames,
+	RunE:              runExportContext,
+}
+
+var importContextCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import contexts from a YAML export",
+	Long: `Import contexts from a file written by 'ascli context export', or from stdin if
+the file is '-'. Secrets in the file are moved to the secret store.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportContext,
+}
+
+// contextExport is the document written by context export
+type contextExport struct {
+	Contexts []Context `json:"contexts"`
+}
+
+func init() {
+	addContextFlags(createContextCmd)
+	addContextFlags(updateContextCmd)
 	createContextCmd.MarkFlagRequired("pulsar-url")
+
+	exportContextCmd.Flags().String("output", "", "File to write to (defaults to stdout)")
+	exportContextCmd.Flags().Bool("redact", false, "Leave secrets such as auth params out of the export")
+
+	importContextCmd.Flags().Bool("overwrite", false, "Replace existing contexts with the same name")
+}
+
+// addContextFlags registers the context setting flags on create and update
+func addContextFlags(cmd *cobra.Command) {
+	cmd.Flags().String("pulsar-url", "", "Pulsar service URL")
+	cmd.Flags().String("auth-plugin", "", "Authentication plugin class name")
+	cmd.Flags().String("auth-params", "", "Authentication parameters (JSON string), kept in the secret store")
+	cmd.Flags().String("admin-url", "", "Admin service URL (derived from the Pulsar URL if not specified)")
+	cmd.Flags().String("token", "", "JWT token to authenticate with, kept in the secret store")
+	cmd.Flags().String("token-file", "", "File containing the JWT token to authenticate with")
+	cmd.Flags().String("tls-cert", "", "Client certificate file for TLS authentication")
+	cmd.Flags().String("tls-key", "", "Client private key file for TLS authentication")
+	cmd.Flags().String("oauth2-issuer-url", "", "OAuth2 issuer URL for the client credentials flow")
+	cmd.Flags().String("oauth2-audience", "", "OAuth2 audience")
+	cmd.Flags().String("oauth2-scope", "", "OAuth2 scope")
+	cmd.Flags().String("oauth2-client-id", "", "OAuth2 client ID")
+	cmd.Flags().String("oauth2-client-secret", "", "OAuth2 client secret, kept in the secret store")
+	cmd.Flags().String("oauth2-key-file", "", "OAuth2 credentials file with client_id and client_secret")
+	cmd.Flags().String("tls-ca", "", "CA certificates file used to verify the server")
+	cmd.Flags().String("tls-trust-certs-file", "", "CA certificates file used to verify the server")
+	cmd.Flags().MarkDeprecated("tls-trust-certs-file", "use --tls-ca instead")
+	cmd.Flags().Bool("tls-allow-insecure", false, "Accept untrusted TLS certificates from the server")
+	cmd.Flags().Bool("tls-validate-hostname", false, "Verify that the server certificate matches its hostname")
+	cmd.Flags().String("kube-context", "", "Kubeconfig context used by agent and tool commands")
+	cmd.Flags().String("namespace", "", "Kubernetes namespace used by agent and tool commands")
+	cmd.Flags().String("secret-store", "", "Where to keep secrets: keyring or file (defaults to the OS keyring, falling back to a file)")
+	cmd.Flags().String("description", "", "Context description")
+}
+
+// applyContextFlags copies the context setting flags that were set on the command line
+// into ctx. Setting a flag to an empty value clears the setting
+func applyContextFlags(cmd *cobra.Command, ctx *Context) error {
+	flags := cmd.Flags()
+	stringFlags := map[string]*string{
+		"pulsar-url":           &ctx.PulsarURL,
+		"auth-plugin":          &ctx.AuthPlugin,
+		"auth-params":          &ctx.AuthParams,
+		"admin-url":            &ctx.AdminURL,
+		"token":                &ctx.Token,
+		"token-file":           &ctx.TokenFile,
+		"tls-cert":             &ctx.TLSCertFile,
+		"tls-key":              &ctx.TLSKeyFile,
+		"oauth2-issuer-url":    &ctx.OAuth2IssuerURL,
+		"oauth2-audience":      &ctx.OAuth2Audience,
+		"oauth2-scope":         &ctx.OAuth2Scope,
+		"oauth2-client-id":     &ctx.OAuth2ClientID,
+		"oauth2-client-secret": &ctx.OAuth2ClientSecret,
+		"oauth2-key-file":      &ctx.OAuth2KeyFile,
+		"tls-ca":               &ctx.TLSTrustCertsFile,
+		"tls-trust-certs-file": &ctx.TLSTrustCertsFile,
+		"kube-context":         &ctx.KubeContext,
+		"namespace":            &ctx.Namespace,
+		"secret-store":         &ctx.SecretStore,
+		"description":          &ctx.Description,
+	}
+	for name, field := range stringFlags {
+		if flags.Changed(name) {
+			*field, _ = flags.GetString(name)
+		}
+	}
+	boolFlags := map[string]*bool{
+		"tls-allow-insecure":    &ctx.TLSAllowInsecure,
+		"tls-validate-hostname": &ctx.TLSValidateHostname,
+	}
+	for name, field := range boolFlags {
+		if flags.Changed(name) {
+			*field, _ = flags.GetBool(name)
+		}
+	}
+
+	if ctx.SecretStore != "" && ctx.SecretStore != secretStoreKeyring && ctx.SecretStore != secretStoreFile {
+		return fmt.Errorf("unknown secret store %q, expected keyring or file", ctx.SecretStore)
+	}
+	if ctx.PulsarURL == "" {
+		return fmt.Errorf("the Pulsar URL of a context cannot be empty")
+	}
+	if _, err := authFromContext(*ctx).method(); err != nil {
+		return err
+	}
+	return nil
 }
 
 // loadContextConfig loads the context configuration from file
@@ -99,33 +280,44 @@ func loadContextConfig() error {
 		return nil
 	}
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get user home directory: %v", err)
+	// ASCLI_CONFIG relocates the contexts file, and the secrets file with it
+	contextConfigPath = os.Getenv("ASCLI_CONFIG")
+	if contextConfigPath == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get user home directory: %v", err)
+		}
+		contextConfigPath = filepath.Join(homeDir, ".ascli", "contexts.json")
 	}
 
-	configDir := filepath.Join(homeDir, ".ascli")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(contextConfigPath), 0700); err != nil {
 		return fmt.Errorf("failed to create config directory: %v", err)
 	}
-
-	contextConfigPath = filepath.Join(configDir, "contexts.json")
-	contextConfig = &ContextConfig{
+	config := &ContextConfig{
 		Contexts: make(map[string]Context),
 	}
 
-	// Try to load existing config
-	if _, err := os.Stat(contextConfigPath); err == nil {
-		data, err := os.ReadFile(contextConfigPath)
-		if err != nil {
-			return fmt.Errorf("failed to read config file: %v", err)
+	// Try to load existing config. A config file that cannot be parsed is an error
+	// rather than a reason to fall back to defaults
+	data, err := os.ReadFile(contextConfigPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, config); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %v", contextConfigPath, err)
 		}
-
-		if err := json.Unmarshal(data, contextConfig); err != nil {
-			return fmt.Errorf("failed to parse config file: %v", err)
+		if config.Contexts == nil {
+			config.Contexts = make(map[string]Context)
+		}
+		for name, ctx := range config.Contexts {
+			if ctx.Name != name {
+				return fmt.Errorf("invalid config file %s: context '%s' is stored under the name '%s'", contextConfigPath, ctx.Name, name)
+			}
 		}
 	}
 
+	contextConfig = config
 	return nil
 }
 
@@ -135,31 +327,102 @@ func saveContextConfig() error {
 		return fmt.Errorf("context config not initialized")
 	}
 
+	// Move secrets out of the contexts file
+	for name, ctx := range contextConfig.Contexts {
+		secrets := ctx.secrets()
+		if secrets.isEmpty() {
+			continue
+		}
+		store, err := storeContextSecrets(name, secrets, ctx.SecretStore)
+		if err != nil {
+			return fmt.Errorf("failed to store secrets of context '%s': %v", name, err)
+		}
+		ctx.SecretStore = store
+		ctx.setSecrets(contextSecrets{})
+		contextConfig.Contexts[name] = ctx
+	}
+
 	data, err := json.MarshalIndent(contextConfig, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %v", err)
 	}
 
-	if err := os.WriteFile(contextConfigPath, data, 0644); err != nil {
+	if err := writePrivateFile(contextConfigPath, data); err != nil {
 		return fmt.Errorf("failed to write config file: %v", err)
 	}
 
 	return nil
 }
 
-// getCurrentContext returns the current context configuration
+// loadSecrets fills in the secrets of a context from its secret store
+func loadSecrets(ctx *Context) error {
+	if ctx.SecretStore == "" || !ctx.secrets().isEmpty() {
+		return nil
+	}
+	store, err := openSecretStore(ctx.SecretStore)
+	if err != nil {
+		return fmt.Errorf("failed to open secret store of context '%s': %v", ctx.Name, err)
+	}
+	secrets, err := store.get(ctx.Name)
+	if err != nil {
+		return fmt.Errorf("failed to read secrets of context '%s': %v", ctx.Name, err)
+	}
+	ctx.setSecrets(secrets)
+	return nil
+}
+
+// removeSecrets deletes the secrets of a context from its secret store
+func removeSecrets(ctx Context) error {
+	if ctx.SecretStore == "" {
+		return nil
+	}
+	store, err := openSecretStore(ctx.SecretStore)
+	if err != nil {
+		return err
+	}
+	return store.remove(ctx.Name)
+}
+
+// activeContextName returns the context selected for this invocation: the --context
+// flag, then ASCLI_CONTEXT, then the current context of the config file
+func activeContextName() string {
+	name, _ := activeContextSource()
+	return name
+}
+
+// activeContextSource returns the selected context and where it was selected
+func activeContextSource() (string, string) {
+	if contextOverride != "" {
+		return contextOverride, "--context flag"
+	}
+	if name := os.Getenv("ASCLI_CONTEXT"); name != "" {
+		return name, "ASCLI_CONTEXT"
+	}
+	return contextConfig.CurrentContext, "current context"
+}
+
+// getCurrentContext returns the context configuration selected for this invocation,
+// or nil if no context is selected. A selected context that does not exist is an error
 func getCurrentContext() (*Context, error) {
 	if err := loadContextConfig(); err != nil {
 		return nil, err
 	}
 
-	if contextConfig.CurrentContext == "" {
-		return nil, fmt.Errorf("no current context set")
+	name, source := activeContextSource()
+	if name == "" {
+		return nil, nil
 	}
 
-	ctx, exists := contextConfig.Contexts[contextConfig.CurrentContext]
+	ctx, exists := contextConfig.Contexts[name]
 	if !exists {
-		return nil, fmt.Errorf("current context '%s' not found", contextConfig.CurrentContext)
+		return nil, fmt.Errorf("context '%s' (selected by %s) not found in %s", name, source, contextConfigPath)
+	}
+	if ctx.PulsarURL == "" {
+		return nil, fmt.Errorf("context '%s' has no pulsar_url in %s", name, contextConfigPath)
+	}
+
+	if err := loadSecrets(&ctx); err != nil {
+		return nil, err
 	}
 
 	return &ctx, nil
@@ -177,17 +440,9 @@ func runCreateContext(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("context '%s' already exists", name)
 	}
 
-	pulsarURL, _ := cmd.Flags().GetString("pulsar-url")
-	authPlugin, _ := cmd.Flags().GetString("auth-plugin")
-	authParams, _ := cmd.Flags().GetString("auth-params")
-	description, _ := cmd.Flags().GetString("description")
-
-	context := Context{
-		Name:        name,
-		PulsarURL:   pulsarURL,
-		AuthPlugin:  authPlugin,
-		AuthParams:  authParams,
-		Description: description,
+	context := Context{Name: name}
+	if err := applyContextFlags(cmd, &context); err != nil {
+		return err
 	}
 
 	contextConfig.Contexts[name] = context
@@ -243,17 +498,14 @@ func runListContext(cmd *cobra.Command, args []string) error {
 	fmt.Println("Available contexts:")
 	for name, ctx := range contextConfig.Contexts {
 		current := ""
-		if name == contextConfig.CurrentContext {
+		if name == activeContextName() {
 			current = " (current)"
 		}
 		fmt.Printf("  %s%s\n", name, current)
 		if ctx.Description != "" {
 			fmt.Printf("    Description: %s\n", ctx.Description)
 		}
-		fmt.Printf("    Pulsar URL: %s\n", ctx.PulsarURL)
-		if ctx.AuthPlugin != "" {
-			fmt.Printf("    Auth Plugin: %s\n", ctx.AuthPlugin)
-		}
+		printContextDetails(ctx, "    ")
 		fmt.Println()
 	}
 
@@ -267,7 +519,8 @@ func runDeleteContext(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if _, exists := contextConfig.Contexts[name]; !exists {
+	ctx, exists := contextConfig.Contexts[name]
+	if !exists {
 		return fmt.Errorf("context '%s' not found", name)
 	}
 
@@ -276,6 +529,10 @@ func runDeleteContext(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("cannot delete current context '%s'. Switch to another context first", name)
 	}
 
+	if err := removeSecrets(ctx); err != nil {
+		return fmt.Errorf("failed to remove secrets of context '%s': %v", name, err)
+	}
+
 	delete(contextConfig.Contexts, name)
 
 	if err := saveContextConfig(); err != nil {
@@ -291,24 +548,247 @@ func runGetCurrentContext(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if contextConfig.CurrentContext == "" {
+	name := activeContextName()
+	if name == "" {
 		fmt.Println("No current context set")
 		return nil
 	}
 
-	ctx, exists := contextConfig.Contexts[contextConfig.CurrentContext]
+	ctx, exists := contextConfig.Contexts[name]
 	if !exists {
-		return fmt.Errorf("current context '%s' not found", contextConfig.CurrentContext)
+		return fmt.Errorf("current context '%s' not found", name)
 	}
 
 	fmt.Printf("Current context: %s\n", ctx.Name)
 	if ctx.Description != "" {
 		fmt.Printf("Description: %s\n", ctx.Description)
 	}
-	fmt.Printf("Pulsar URL: %s\n", ctx.PulsarURL)
+	printContextDetails(ctx, "")
+
+	return nil
+}
+
+// printContextDetails prints the settings of a context, without its secrets
+func printContextDetails(ctx Context, indent string) {
+	fmt.Printf("%sPulsar URL: %s\n", indent, ctx.PulsarURL)
+	if ctx.AdminURL != "" {
+		fmt.Printf("%sAdmin URL: %s\n", indent, ctx.AdminURL)
+	}
 	if ctx.AuthPlugin != "" {
-		fmt.Printf("Auth Plugin: %s\n", ctx.AuthPlugin)
+		fmt.Printf("%sAuth Plugin: %s\n", indent, ctx.AuthPlugin)
+	}
+	if ctx.TokenFile != "" {
+		fmt.Printf("%sToken File: %s\n", indent, ctx.TokenFile)
+	}
+	if ctx.TLSCertFile != "" {
+		fmt.Printf("%sTLS Cert: %s\n", indent, ctx.TLSCertFile)
+		fmt.Printf("%sTLS Key: %s\n", indent, ctx.TLSKeyFile)
+	}
+	if ctx.OAuth2IssuerURL != "" {
+		fmt.Printf("%sOAuth2 Issuer URL: %s\n", indent, ctx.OAuth2IssuerURL)
+		if ctx.OAuth2Audience != "" {
+			fmt.Printf("%sOAuth2 Audience: %s\n", indent, ctx.OAuth2Audience)
+		}
+		if ctx.OAuth2ClientID != "" {
+			fmt.Printf("%sOAuth2 Client ID: %s\n", indent, ctx.OAuth2ClientID)
+		}
+		if ctx.OAuth2KeyFile != "" {
+			fmt.Printf("%sOAuth2 Key File: %s\n", indent, ctx.OAuth2KeyFile)
+		}
+	}
+	if ctx.TLSTrustCertsFile != "" {
+		fmt.Printf("%sTLS CA: %s\n", indent, ctx.TLSTrustCertsFile)
+	}
+	if ctx.TLSAllowInsecure {
+		fmt.Printf("%sTLS Allow Insecure: true\n", indent)
+	}
+	if ctx.TLSValidateHostname {
+		fmt.Printf("%sTLS Validate Hostname: true\n", indent)
+	}
+	if ctx.KubeContext != "" {
+		fmt.Printf("%sKube Context: %s\n", indent, ctx.KubeContext)
+	}
+	if ctx.Namespace != "" {
+		fmt.Printf("%sNamespace: %s\n", indent, ctx.Namespace)
+	}
+	if ctx.SecretStore != "" {
+		fmt.Printf("%sSecrets: stored in %s\n", indent, ctx.SecretStore)
+	}
+}
+
+func runUpdateContext(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	if err := loadContextConfig(); err != nil {
+		return err
+	}
+
+	ctx, exists := contextConfig.Contexts[name]
+	if !exists {
+		return fmt.Errorf("context '%s' not found", name)
+	}
+
+	// Secrets are loaded so the authentication settings can be checked as a whole
+	if err := loadSecrets(&ctx); err != nil {
+		return err
+	}
+	previous := ctx
+	if err := applyContextFlags(cmd, &ctx); err != nil {
+		return err
+	}
+
+	// Secrets are rewritten when they or the secret store change
+	secretsChanged := ctx.secrets() != previous.secrets() || ctx.SecretStore != previous.SecretStore
+	if secretsChanged {
+		if err := removeSecrets(previous); err != nil {
+			return fmt.Errorf("failed to remove secrets of context '%s': %v", name, err)
+		}
+	}
+	contextConfig.Contexts[name] = ctx
+
+	if err := saveContextConfig(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Context '%s' updated successfully\n", name)
+	return nil
+}
+
+func runRenameContext(cmd *cobra.Command, args []string) error {
+	oldName, newName := args[0], args[1]
+
+	if err := loadContextConfig(); err != nil {
+		return err
+	}
+
+	ctx, exists := contextConfig.Contexts[oldName]
+	if !exists {
+		return fmt.Errorf("context '%s' not found", oldName)
+	}
+	if _, exists := contextConfig.Contexts[newName]; exists {
+		return fmt.Errorf("context '%s' already exists", newName)
+	}
+
+	// Secrets are stored by context name, so they move with the context. The old
+	// ones are only removed once the renamed context is saved with them
+	if err := loadSecrets(&ctx); err != nil {
+		return err
+	}
+	old := ctx
+
+	ctx.Name = newName
+	delete(contextConfig.Contexts, oldName)
+	contextConfig.Contexts[newName] = ctx
+	if contextConfig.CurrentContext == oldName {
+		contextConfig.CurrentContext = newName
+	}
+
+	if err := saveContextConfig(); err != nil {
+		return err
+	}
+	if err := removeSecrets(old); err != nil {
+		return fmt.Errorf("context '%s' renamed to '%s', but failed to remove the secrets stored for '%s': %v", oldName, newName, oldName, err)
+	}
+
+	fmt.Printf("Context '%s' renamed to '%s'\n", oldName, newName)
+	return nil
+}
+
+func runExportContext(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	redact, _ := cmd.Flags().GetBool("redact")
+
+	if err := loadContextConfig(); err != nil {
+		return err
+	}
+
+	names := args
+	if len(names) == 0 {
+		for name := range contextConfig.Contexts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var export contextExport
+	for _, name := range names {
+		ctx, exists := contextConfig.Contexts[name]
+		if !exists {
+			return fmt.Errorf("context '%s' not found", name)
+		}
+		if redact {
+			ctx.setSecrets(contextSecrets{})
+		} else if err := loadSecrets(&ctx); err != nil {
+			return err
+		}
+		// Where secrets are stored is specific to this machine
+		ctx.SecretStore = ""
+		export.Contexts = append(export.Contexts, ctx)
+	}
+
+	data, err := yaml.Marshal(export)
+	if err != nil {
+		return fmt.Errorf("failed to marshal contexts: %v", err)
+	}
+
+	if output == "" {
+		fmt.Print(string(data))
+		return nil
+	}
+	if err := writePrivateFile(output, data); err != nil {
+		return fmt.Errorf("failed to write export file: %v", err)
+	}
+	fmt.Printf("Exported %d contexts to %s\n", len(export.Contexts), output)
+	return nil
+}
+
+func runImportContext(cmd *cobra.Command, args []string) error {
+	overwrite, _ := cmd.Flags().GetBool("overwrite")
+
+	data, err := readInput(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read import file: %v", err)
+	}
+
+	var export contextExport
+	if err := yaml.UnmarshalStrict(data, &export); err != nil {
+		return fmt.Errorf("failed to parse import file: %v", err)
+	}
+
+	if err := loadContextConfig(); err != nil {
+		return err
+	}
+
+	// Validate everything before changing the configuration
+	for _, ctx := range export.Contexts {
+		if ctx.Name == "" {
+			return fmt.Errorf("import file contains a context without a name")
+		}
+		if ctx.PulsarURL == "" {
+			return fmt.Errorf("context '%s' in the import file has no Pulsar URL", ctx.Name)
+		}
+		if _, exists := contextConfig.Contexts[ctx.Name]; exists && !overwrite {
+			return fmt.Errorf("context '%s' already exists, use --overwrite to replace it", ctx.Name)
+		}
+	}
+
+	for _, ctx := range export.Contexts {
+		if existing, exists := contextConfig.Contexts[ctx.Name]; exists {
+			if err := removeSecrets(existing); err != nil {
+				return fmt.Errorf("failed to remove secrets of context '%s': %v", ctx.Name, err)
+			}
+		}
+		ctx.SecretStore = ""
+		contextConfig.Contexts[ctx.Name] = ctx
+		if contextConfig.CurrentContext == "" {
+			contextConfig.CurrentContext = ctx.Name
+		}
+	}
+
+	if err := saveContextConfig(); err != nil {
+		return err
 	}
 
+	fmt.Printf("Imported %d contexts\n", len(export.Contexts))
 	return nil
 }
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	asv1alpha1 "github.com/agentstream/agentstream/operator/api/v1alpha1"
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/spf13/cobra"
+)
+
+// Properties the agent runtime sets on a request published to the dead-letter topic
+const (
+	deadLetterErrorProperty     = "error"
+	deadLetterErrorTypeProperty = "error_type"
+	deadLetterAgentProperty     = "agent"
+	deadLetterFailedAtProperty  = "failed_at"
+)
+
+var deadLetterProperties = []string{
+	deadLetterErrorProperty,
+	deadLetterErrorTypeProperty,
+	deadLetterAgentProperty,
+	deadLetterFailedAtProperty,
+}
+
+var (
+	dlqKubeOpts  kubeOptions
+	dlqPulsarURL string
+	dlqAuth      authFlags
+
+	dlqFrom     string
+	dlqTimezone string
+	dlqNum      int
+	dlqTimeout  time.Duration
+	dlqAll      bool
+)
+
+var dlqCmd = &cobra.Command{
+	Use:   "dlq",
+	Short: "Inspect and replay the failed requests of an agent",
+	Long: `Browse the requests an agent failed to process and re-submit them.
+
+When processing a request raises, for example in the postProcess jsonnet, the agent
+publishes the request to its dead-letter topic with the error in the message
+properties. The topic is spec.deadLetterTopic, or the default the operator reports
+in status.deadLetterTopic, persistent://public/default/dead-letter-<namespace>-<name>
+unless its config sets another pattern. The cluster is selected like for 'ascli agent'.
+
+Examples:
+  ascli dlq list my-agent
+  ascli dlq list my-agent --from -1h
+  ascli dlq show my-agent 123:45:-1
+  ascli dlq replay my-agent 123:45:-1 123:46:-1
+  ascli dlq replay my-agent --all --from -1h`,
+}
+
+var listDLQCmd = &cobra.Command{
+	Use:               "list [agent]",
+	Short:             "List the failed requests of an agent with their error",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeAgentNames(&dlqKubeOpts, 1),
+	RunE:              runListDLQ,
+}
+
+var showDLQCmd = &cobra.Command{
+	Use:               "show [agent] [message-id]",
+	Short:             "Show a failed request with its error and payload",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeAgentNames(&dlqKubeOpts, 1),
+	RunE:              runShowDLQ,
+}
+
+var replayDLQCmd = &cobra.Command{
+	Use:   "replay [agent] [message-id...]",
+	Short: "Re-submit failed requests to the agent's request topic",
+	Long: `Re-submit failed requests to the request topic of the agent.
+
+The agent dead-letters a request with its payload and properties, such as request_id
+and response_topic, and these are sent again without the error properties, so the
+reply goes where the request asked for it. The message key is not kept by the agent.
+Give the message IDs to replay, or --all to replay every failed request from --from on.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeAgentNames(&dlqKubeOpts, 1),
+	RunE:              runReplayDLQ,
+}
+
+func init() {
+	rootCmd.AddCommand(dlqCmd)
+	for _, cmd := range []*cobra.Command{listDLQCmd, showDLQCmd, replayDLQCmd} {
+		dlqCmd.AddCommand(cmd)
+		cmd.Flags().StringVar(&dlqPulsarURL, "pulsar-url", "", "Service URL (overrides context)")
+		addAuthFlags(cmd, &dlqAuth)
+		cmd.Flags().DurationVar(&dlqTimeout, "timeout", 30*time.Second, "Maximum time to spend reading the dead-letter topic")
+	}
+
+	addKubeFlags(dlqCmd, &dlqKubeOpts)
+
+	for _, cmd := range []*cobra.Command{listDLQCmd, replayDLQCmd} {
+		cmd.Flags().StringVar(&dlqFrom, "from", "earliest", "Start position: earliest, a message ID, a time or a relative duration like -15m")
+		cmd.Flags().StringVar(&dlqTimezone, "timezone", "Local", "Time zone used to parse times without an offset")
+	}
+	listDLQCmd.Flags().IntVar(&dlqNum, "num", 0, "Maximum number of failed requests to list (0 for no limit)")
+	replayDLQCmd.Flags().BoolVar(&dlqAll, "all", false, "Replay every failed request from --from on")
+}
+
+// agentDeadLetterTopic returns the dead-letter topic of an agent, the one reported by
+// the operator or else its built-in default
+func agentDeadLetterTopic(a *asv1alpha1.Agent) string {
+	if a.Spec.DeadLetterTopic != "" {
+		return a.Spec.DeadLetterTopic
+	}
+	if a.Status.DeadLetterTopic != "" {
+		return a.Status.DeadLetterTopic
+	}
+	return fmt.Sprintf("persistent://public/default/dead-letter-%s-%s", a.Namespace, a.Name)
+}
+
+// dlqTarget fetches the agent and connects to Pulsar
+func dlqTarget(name string) (*asv1alpha1.Agent, pulsar.Client, error) {
+	kube, err := newKubeClient(dlqKubeOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := kube.getAgent(context.Background(), kube.namespace, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get agent '%s': %v", name, err)
+	}
+
+	// Get configuration from context or command line
+	cfg, err := resolveConfig(configFlags{pulsarURL: dlqPulsarURL, auth: dlqAuth})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get context configuration: %v", err)
+	}
+
+	// Create client
+	clientOpts, err := buildClientOptions(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build client options: %v", err)
+	}
+
+	client, err := pulsar.NewClient(clientOpts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create client: %v", err)
+	}
+	return a, client, nil
+}
+
+// readDeadLetters reads the dead-letter topic from start until its end, or until num
+// messages are read if num is positive
+func readDeadLetters(client pulsar.Client, topic string, start startPosition, num int) ([]pulsar.Message, error) {
+	reader, err := newTopicReader(client, topic, start)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dlqTimeout)
+	defer cancel()
+
+	var msgs []pulsar.Message
+	for (num <= 0 || len(msgs) < num) && reader.HasNext() {
+		msg, err := reader.Next(ctx)
+		if err != nil {
+			if err == context.DeadlineExceeded {
+				fmt.Fprintf(os.Stderr, "Timeout reached after reading %d messages\n", len(msgs))
+				break
+			}
+			return nil, fmt.Errorf("failed to read message: %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// readDeadLetter reads the message with the given ID from the dead-letter topic
+func readDeadLetter(client pulsar.Client, topic, value string) (pulsar.Message, error) {
+	id, err := parseMessageID(value)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := readDeadLetters(client, topic, startPosition{messageID: id}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 || msgs[0].ID().LedgerID() != id.LedgerID() || msgs[0].ID().EntryID() != id.EntryID() {
+		return nil, fmt.Errorf("message %s not found on dead-letter topic '%s'", value, topic)
+	}
+	return msgs[0], nil
+}
+
+// dlqStartPosition parses --from
+func dlqStartPosition() (startPosition, error) {
+	loc, err := loadTimezone(dlqTimezone)
+	if err != nil {
+		return startPosition{}, err
+	}
+	return parseStartPosition(dlqFrom, loc, time.Now())
+}
+
+// failedAt returns when the agent dead-lettered a message, falling back to its
+// publish time
+func failedAt(msg pulsar.Message) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, msg.Properties()[deadLetterFailedAtProperty]); err == nil {
+		return t
+	}
+	return msg.PublishTime()
+}
+
+// truncate shortens a single line value for table output
+func truncate(value string, width int) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if len(value) <= width {
+		return value
+	}
+	return value[:width-3] + "..."
+}
+
+func runListDLQ(cmd *cobra.Command, args []string) error {
+	start, err := dlqStartPosition()
+	if err != nil {
+		return err
+	}
+	a, client, err := dlqTarget(args[0])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	topic := agentDeadLetterTopic(a)
+	msgs, err := readDeadLetters(client, topic, start, dlqNum)
+	if err != nil {
+		return err
+	}
+	if len(msgs) == 0 {
+		fmt.Printf("No failed requests on dead-letter topic '%s'\n", topic)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "MESSAGE ID\tFAILED AT\tERROR TYPE\tERROR")
+	for _, msg := range msgs {
+		properties := msg.Properties()
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			msg.ID().String(),
+			failedAt(msg).Local().Format("2006-01-02 15:04:05"),
+			valueOrNone(properties[deadLetterErrorTypeProperty]),
+			valueOrNone(truncate(properties[deadLetterErrorProperty], 80)))
+	}
+	return w.Flush()
+}
+
+func runShowDLQ(cmd *cobra.Command, args []string) error {
+	a, client, err := dlqTarget(args[0])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	msg, err := readDeadLetter(client, agentDeadLetterTopic(a), args[1])
+	if err != nil {
+		return err
+	}
+
+	properties := msg.Properties()
+	fmt.Printf("Message ID: %s\n", msg.ID().String())
+	fmt.Printf("Topic: %s\n", msg.Topic())
+	fmt.Printf("Failed At: %s\n", failedAt(msg).Local().Format(time.RFC3339))
+	fmt.Printf("Error Type: %s\n", valueOrNone(properties[deadLetterErrorTypeProperty]))
+	if msg.Key() != "" {
+		fmt.Printf("Key: %s\n", msg.Key())
+	}
+
+	fmt.Println("\nError:")
+	for _, line := range strings.Split(valueOrNone(properties[deadLetterErrorProperty]), "\n") {
+		fmt.Printf("  %s\n", line)
+	}
+
+	var keys []string
+	for k := range properties {
+		if !isDeadLetterProperty(k) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		fmt.Println("\nProperties:")
+		for _, k := range keys {
+			fmt.Printf("  %s=%s\n", k, properties[k])
+		}
+	}
+
+	fmt.Println("\nRequest:")
+	var data interface{}
+	if err := json.Unmarshal(msg.Payload(), &data); err == nil {
+		pretty, _ := json.MarshalIndent(data, "", "  ")
+		fmt.Println(string(pretty))
+	} else {
+		fmt.Println(string(msg.Payload()))
+	}
+	return nil
+}
+
+// isDeadLetterProperty reports whether a property was added by the agent runtime
+// when dead-lettering the request
+func isDeadLetterProperty(key string) bool {
+	for _, k := range deadLetterProperties {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func runReplayDLQ(cmd *cobra.Command, args []string) error {
+	ids := args[1:]
+	if dlqAll == (len(ids) > 0) {
+		return fmt.Errorf("give the message IDs to replay, or --all")
+	}
+	start, err := dlqStartPosition()
+	if err != nil {
+		return err
+	}
+
+	a, client, err := dlqTarget(args[0])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	requestTopic := sourceTopic(a.Spec.RequestSource)
+	if requestTopic == "" {
+		return fmt.Errorf("agent '%s' has no request topic (spec.requestSource.pulsar.topic)", a.Name)
+	}
+	topic := agentDeadLetterTopic(a)
+
+	var msgs []pulsar.Message
+	if dlqAll {
+		msgs, err = readDeadLetters(client, topic, start, 0)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, id := range ids {
+			msg, err := readDeadLetter(client, topic, id)
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+	if len(msgs) == 0 {
+		fmt.Printf("No failed requests on dead-letter topic '%s'\n", topic)
+		return nil
+	}
+
+	producer, err := client.CreateProducer(pulsar.ProducerOptions{
+		Topic: requestTopic,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create producer: %v", err)
+	}
+	defer producer.Close()
+
+	sent := 0
+	for _, msg := range msgs {
+		_, err := producer.Send(context.Background(), &pulsar.ProducerMessage{
+			Payload:     msg.Payload(),
+			Key:         msg.Key(),
+			OrderingKey: msg.OrderingKey(),
+			Properties:  replayProperties(msg.Properties(), deadLetterProperties, nil),
+		})
+		if err != nil {
+			fmt.Printf("Failed to replay message %s: %v\n", msg.ID().String(), err)
+			continue
+		}
+		sent++
+		fmt.Printf("Message %s replayed to topic '%s'\n", msg.ID().String(), requestTopic)
+	}
+
+	fmt.Fprintf(os.Stderr, "Replayed %d of %d failed requests to topic '%s'\n", sent, len(msgs), requestTopic)
+	return nil
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/spf13/cobra"
+)
+
+const (
+	dumpFormatJSONL = "jsonl"
+	dumpFormatTar   = "tar"
+
+	// dumpManifestName is the name of the manifest entry in tar dumps
+	dumpManifestName = "manifest.json"
+	// dumpMessagesDir is the directory holding message entries in tar dumps
+	dumpMessagesDir = "messages"
+)
+
+var (
+	dumpPulsarURL string
+	dumpTopic     string
+	dumpFrom      string
+	dumpUntil     string
+	dumpTimezone  string
+	dumpNum       int
+	dumpOutput    string
+	dumpFormat    string
+	dumpTimeout   time.Duration
+	dumpAuth      authFlags
+)
+
+// dumpRecord is a single captured message. The payload is kept as raw bytes
+// (base64 in JSON) so it can be replayed exactly
+type dumpRecord struct {
+	MessageID   string            `json:"message_id"`
+	Topic       string            `json:"topic"`
+	PublishTime time.Time         `json:"publish_time"`
+	EventTime   *time.Time        `json:"event_time,omitempty"`
+	Key         string            `json:"key,omitempty"`
+	OrderingKey string            `json:"ordering_key,omitempty"`
+	Properties  map[string]string `json:"properties,omitempty"`
+	Payload     []byte            `json:"payload,omitempty"`
+}
+
+// dumpManifest describes the contents of a tar dump
+type dumpManifest struct {
+	Topic     string    `json:"topic"`
+	From      string    `json:"from"`
+	Until     string    `json:"until,omitempty"`
+	Count     int       `json:"count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var dumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Export a range of messages from a topic to a file",
+	Long: `Export a range of messages from a topic to a JSONL or tar file.
+
+Each message is captured with its payload, properties, key, ordering key, event time
+and publish time, so it can be re-published exactly with 'ascli replay'. The range
+starts at --from and ends at --until (a time or message ID), --num messages, or the
+end of the topic, whichever comes first. --from and --until accept the same forms as
+'ascli read'. A message ID --until bounds a single partition, so on a partitioned
+topic --from must be a message ID of the same partition.
+
+JSONL dumps hold one message per line with a base64 payload. Tar dumps hold a
+manifest.json and, per message, messages/<n>.json with the metadata and
+messages/<n>.payload with the raw payload.
+
+Examples:
+  ascli dump --topic my-topic --from -1h --output incident.jsonl
+  ascli dump --topic my-topic --from 2024-01-01T12:00:00Z --until 2024-01-01T12:30:00Z --output incident.tar
+  ascli dump --topic my-topic --from 123:45:-1 --until 123:90:-1 --output range.jsonl
+  ascli dump --topic my-partitioned-topic --from 123:45:2 --until 123:90:2 --output range.jsonl
+  ascli dump --topic my-topic --num 100 --output - > sample.jsonl`,
+	RunE: runDump,
+}
+
+func init() {
+	rootCmd.AddCommand(dumpCmd)
+
+	dumpCmd.Flags().StringVar(&dumpPulsarURL, "pulsar-url", "", "Service URL (overrides context)")
+	dumpCmd.Flags().StringVar(&dumpTopic, "topic", "", "Topic to dump (required)")
+	dumpCmd.Flags().StringVar(&dumpFrom, "from", "earliest", "Start position: earliest, latest, a message ID, a time or a relative duration like -15m")
+	dumpCmd.Flags().StringVar(&dumpUntil, "until", "", "End position (inclusive): a message ID or a time")
+	dumpCmd.Flags().StringVar(&dumpTimezone, "timezone", "Local", "Time zone used to parse times without an offset")
+	dumpCmd.Flags().IntVar(&dumpNum, "num", 0, "Maximum number of messages to dump (0 for no limit)")
+	dumpCmd.Flags().StringVar(&dumpOutput, "output", "", "Output file, or '-' for stdout (required)")
+	dumpCmd.Flags().StringVar(&dumpFormat, "format", "", "Output format: jsonl or tar (derived from the output file extension if not specified)")
+	dumpCmd.Flags().DurationVar(&dumpTimeout, "timeout", 30*time.Second, "Maximum time to spend reading messages")
+	addAuthFlags(dumpCmd, &dumpAuth)
+
+	dumpCmd.MarkFlagRequired("topic")
+	registerTopicCompletion(dumpCmd, &dumpPulsarURL, &dumpAuth)
+	dumpCmd.MarkFlagRequired("output")
+}
+
+// resolveDumpFormat returns the explicit format, or derives it from the file name
+func resolveDumpFormat(format, file string) (string, error) {
+	if format == "" {
+		if strings.HasSuffix(file, ".tar") {
+			return dumpFormatTar, nil
+		}
+		return dumpFormatJSONL, nil
+	}
+	switch format {
+	case dumpFormatJSONL, dumpFormatTar:
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported dump format %q, expected jsonl or tar", format)
+}
+
+// newDumpRecord captures a message as a dump record
+func newDumpRecord(msg pulsar.Message) dumpRecord {
+	record := dumpRecord{
+		MessageID:   msg.ID().String(),
+		Topic:       msg.Topic(),
+		PublishTime: msg.PublishTime(),
+		Key:         msg.Key(),
+		OrderingKey: msg.OrderingKey(),
+		Properties:  msg.Properties(),
+		Payload:     msg.Payload(),
+	}
+	if eventTime := msg.EventTime(); !eventTime.IsZero() {
+		record.EventTime = &eventTime
+	}
+	return record
+}
+
+// writeDumpJSONL writes records as JSON Lines
+func writeDumpJSONL(w io.Writer, records []dumpRecord) error {
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDumpTar writes records as a tar archive with a manifest
+func writeDumpTar(w io.Writer, manifest dumpManifest, records []dumpRecord) error {
+	tw := tar.NewWriter(w)
+	writeEntry := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: manifest.CreatedAt,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeEntry(dumpManifestName, data); err != nil {
+		return err
+	}
+
+	for i, record := range records {
+		base := path.Join(dumpMessagesDir, fmt.Sprintf("%06d", i+1))
+		payload := record.Payload
+		record.Payload = nil
+		meta, err := json.MarshalIndent(record, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeEntry(base+".json", meta); err != nil {
+			return err
+		}
+		if err := writeEntry(base+".payload", payload); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// readDumpFile reads the records of a JSONL or tar dump, or stdin if path is '-'
+func readDumpFile(file, format string) ([]dumpRecord, error) {
+	format, err := resolveDumpFormat(format, file)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if format == dumpFormatTar {
+		return readDumpTar(r)
+	}
+
+	var records []dumpRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var record dumpRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			return nil, fmt.Errorf("invalid record on line %d: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// readDumpTar reads the message entries of a tar dump in order
+func readDumpTar(r io.Reader) ([]dumpRecord, error) {
+	metas := make(map[string]dumpRecord)
+	payloads := make(map[string][]byte)
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar dump: %v", err)
+		}
+		if path.Dir(hdr.Name) != dumpMessagesDir {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		name := path.Base(hdr.Name)
+		switch path.Ext(name) {
+		case ".json":
+			var record dumpRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return nil, fmt.Errorf("invalid record %s: %v", hdr.Name, err)
+			}
+			metas[strings.TrimSuffix(name, ".json")] = record
+		case ".payload":
+			payloads[strings.TrimSuffix(name, ".payload")] = data
+		}
+	}
+
+	ids := make([]string, 0, len(metas))
+	for id := range metas {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	records := make([]dumpRecord, 0, len(ids))
+	for _, id := range ids {
+		record := metas[id]
+		record.Payload = payloads[id]
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func runDump(cmd *cobra.Command, args []string) error {
+	format, err := resolveDumpFormat(dumpFormat, dumpOutput)
+	if err != nil {
+		return err
+	}
+
+	loc, err := loadTimezone(dumpTimezone)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	start, err := parseStartPosition(dumpFrom, loc, now)
+	if err != nil {
+		return err
+	}
+	end, err := parseEndPosition(dumpUntil, loc, now)
+	if err != nil {
+		return err
+	}
+
+	// Get configuration from context or command line
+	cfg, err := resolveConfig(configFlags{pulsarURL: dumpPulsarURL, auth: dumpAuth})
+	if err != nil {
+		return fmt.Errorf("failed to get context configuration: %v", err)
+	}
+
+	// Create client
+	clientOpts, err := buildClientOptions(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build client options: %v", err)
+	}
+
+	client, err := pulsar.NewClient(clientOpts)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	reader, err := newTopicReader(client, dumpTopic, start)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if end.messageID != nil {
+		if !reader.readsPartition(end.messageID.PartitionIdx()) {
+			return fmt.Errorf("--until %s is not a message ID of a partition of topic '%s' that is read", dumpUntil, dumpTopic)
+		}
+		// The bound would stop the merged read of all partitions at an arbitrary point
+		if len(reader.partitions) > 1 {
+			return fmt.Errorf("--until %s bounds one partition of topic '%s', --from must be a message ID of the same partition", dumpUntil, dumpTopic)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dumpTimeout)
+	defer cancel()
+
+	// Read until the end bound, the limit, or the end of the topic
+	var records []dumpRecord
+	for (dumpNum <= 0 || len(records) < dumpNum) && reader.HasNext() {
+		msg, err := reader.Next(ctx)
+		if err != nil {
+			if err == context.DeadlineExceeded {
+				fmt.Fprintf(os.Stderr, "Timeout reached after reading %d messages\n", len(records))
+				break
+			}
+			return fmt.Errorf("failed to read message: %v", err)
+		}
+		if end.after(msg) {
+			break
+		}
+		records = append(records, newDumpRecord(msg))
+	}
+
+	var w io.Writer = os.Stdout
+	if dumpOutput != "-" {
+		f, err := os.Create(dumpOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if format == dumpFormatTar {
+		err = writeDumpTar(w, dumpManifest{
+			Topic:     dumpTopic,
+			From:      dumpFrom,
+			Until:     dumpUntil,
+			Count:     len(records),
+			CreatedAt: time.Now().UTC(),
+		}, records)
+	} else {
+		err = writeDumpJSONL(w, records)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write dump: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Dumped %d messages from topic '%s'\n", len(records), dumpTopic)
+	return nil
+}
@@ -47,7 +47,7 @@ echo "   ./ascli read --topic test-topic --num 10"
 echo
 
 echo "4. Read messages from a specific time:"
-echo "   ./ascli read --topic test-topic --seek-time \"2024-01-01 12:00:00\" --num 5"
+echo "   ./ascli read --topic test-topic --from \"2024-01-01 12:00:00\" --num 5"
 echo
 
 echo "5. Send RPC request:"
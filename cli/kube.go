@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	fsv1alpha1 "github.com/FunctionStream/function-stream/operator/api/v1alpha1"
+	asv1alpha1 "github.com/agentstream/agentstream/operator/api/v1alpha1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var (
+	agentGVR    = asv1alpha1.GroupVersion.WithResource("agents")
+	functionGVR = fsv1alpha1.GroupVersion.WithResource("functions")
+	packageGVR  = fsv1alpha1.GroupVersion.WithResource("packages")
+)
+
+// sourceTopic returns the Pulsar topic of a source, or an empty string
+func sourceTopic(s *fsv1alpha1.SourceSpec) string {
+	if s == nil || s.Pulsar == nil {
+		return ""
+	}
+	return s.Pulsar.Topic
+}
+
+// kubeOptions holds the flags that select the Kubernetes cluster and namespace
+type kubeOptions struct {
+	kubeconfig  string
+	kubeContext string
+	namespace   string
+}
+
+// kubeClient is a dynamic client bound to a default namespace
+type kubeClient struct {
+	dynamic   dynamic.Interface
+	namespace string
+}
+
+// newKubeClient loads the kubeconfig (KUBECONFIG or ~/.kube/config unless
+// overridden) and resolves the namespace from the flags, the environment, the ascli
+// context or the kube context
+func newKubeClient(opts kubeOptions) (*kubeClient, error) {
+	// The kube context and namespace resolve like the other settings
+	cfg, err := resolveConfig(configFlags{kubeContext: opts.kubeContext, namespace: opts.namespace})
+	if err != nil {
+		return nil, err
+	}
+	opts.kubeContext = cfg.kubeContext.value
+	opts.namespace = cfg.namespace.value
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if opts.kubeconfig != "" {
+		rules.ExplicitPath = opts.kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: opts.kubeContext}
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	restConfig, err := loader.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+
+	namespace := opts.namespace
+	if namespace == "" {
+		namespace, _, err = loader.Namespace()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve namespace: %v", err)
+		}
+	}
+
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+	return &kubeClient{dynamic: client, namespace: namespace}, nil
+}
+
+// get fetches a resource and decodes it into out
+func (c *kubeClient) get(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string, out interface{}) error {
+	obj, err := c.dynamic.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), out)
+}
+
+// getAgent fetches an Agent by name
+func (c *kubeClient) getAgent(ctx context.Context, namespace, name string) (*asv1alpha1.Agent, error) {
+	var a asv1alpha1.Agent
+	if err := c.get(ctx, agentGVR, namespace, name, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// listAgents lists Agents in a namespace, or in all namespaces if namespace is empty
+func (c *kubeClient) listAgents(ctx context.Context, namespace string) ([]asv1alpha1.Agent, error) {
+	list, err := c.dynamic.Resource(agentGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	agents := make([]asv1alpha1.Agent, 0, len(list.Items))
+	for _, item := range list.Items {
+		var a asv1alpha1.Agent
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), &a); err != nil {
+			return nil, fmt.Errorf("failed to decode agent '%s': %v", item.GetName(), err)
+		}
+		agents = append(agents, a)
+	}
+	return agents, nil
+}
+
+// createAgent creates an Agent and returns the stored object
+func (c *kubeClient) createAgent(ctx context.Context, a *asv1alpha1.Agent) (*asv1alpha1.Agent, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(a)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := c.dynamic.Resource(agentGVR).Namespace(a.Namespace).Create(ctx, &unstructured.Unstructured{Object: content}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var created asv1alpha1.Agent
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// setAgentSuspended sets or clears spec.suspended of an Agent. The operator deletes
+// the Function of a suspended agent and creates it again once it is resumed
+func (c *kubeClient) setAgentSuspended(ctx context.Context, namespace, name string, suspended bool) error {
+	// Resuming removes the field rather than setting it to false
+	var value interface{}
+	if suspended {
+		value = true
+	}
+	patch, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"suspended": value}})
+	if err != nil {
+		return err
+	}
+	_, err = c.dynamic.Resource(agentGVR).Namespace(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// deleteAgent deletes an Agent. The operator-owned Function is garbage collected
+func (c *kubeClient) deleteAgent(ctx context.Context, namespace, name string) error {
+	return c.dynamic.Resource(agentGVR).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// getFunction fetches a Function by name
+func (c *kubeClient) getFunction(ctx context.Context, namespace, name string) (*fsv1alpha1.Function, error) {
+	var f fsv1alpha1.Function
+	if err := c.get(ctx, functionGVR, namespace, name, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// listFunctions lists Functions in a namespace, or in all namespaces if namespace is empty
+func (c *kubeClient) listFunctions(ctx context.Context, namespace string) ([]fsv1alpha1.Function, error) {
+	list, err := c.dynamic.Resource(functionGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	functions := make([]fsv1alpha1.Function, 0, len(list.Items))
+	for _, item := range list.Items {
+		var f fsv1alpha1.Function
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), &f); err != nil {
+			return nil, fmt.Errorf("failed to decode function '%s': %v", item.GetName(), err)
+		}
+		functions = append(functions, f)
+	}
+	return functions, nil
+}
+
+// getPackage fetches a Package by name
+func (c *kubeClient) getPackage(ctx context.Context, namespace, name string) (*fsv1alpha1.Package, error) {
+	var p fsv1alpha1.Package
+	if err := c.get(ctx, packageGVR, namespace, name, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// getFunctionModule fetches the Package module a Function runs, resolving the package
+// namespace the same way the operator does
+func (c *kubeClient) getFunctionModule(ctx context.Context, f *fsv1alpha1.Function) (*fsv1alpha1.Module, error) {
+	pkgNamespace := f.Spec.PackageRef.Namespace
+	if pkgNamespace == "" {
+		pkgNamespace = f.Namespace
+	}
+	p, err := c.getPackage(ctx, pkgNamespace, f.Spec.PackageRef.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get package %s: %v", f.Spec.PackageRef.Name, err)
+	}
+	module, ok := p.Spec.Modules[f.Spec.Module]
+	if !ok {
+		return nil, fmt.Errorf("module %s not found in package %s", f.Spec.Module, f.Spec.PackageRef.Name)
+	}
+	return &module, nil
+}
+
+// addKubeFlags registers the cluster selection flags on a command
+func addKubeFlags(cmd *cobra.Command, opts *kubeOptions) {
+	cmd.PersistentFlags().StringVar(&opts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file (defaults to KUBECONFIG or ~/.kube/config)")
+	cmd.PersistentFlags().StringVar(&opts.kubeContext, "kube-context", "", "Kubeconfig context to use (defaults to the current kube context)")
+	cmd.PersistentFlags().StringVarP(&opts.namespace, "namespace", "n", "", "Kubernetes namespace (defaults to the kube context namespace)")
+}
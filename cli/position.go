@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+// localTimeLayout is the layout accepted for date-times without a zone offset
+const localTimeLayout = "2006-01-02 15:04:05"
+
+// messageIDPattern matches the ledgerId:entryId[:partition[:batchIndex]] form of
+// message IDs. Non-partitioned topics print -1 as the partition
+var messageIDPattern = regexp.MustCompile(`^\d+:\d+(:-?\d+){0,2}$`)
+
+// startPosition is where a read starts: a message ID or a publish time
+type startPosition struct {
+	messageID pulsar.MessageID
+	time      time.Time
+}
+
+// isTime reports whether the position is a publish time rather than a message ID
+func (p startPosition) isTime() bool {
+	return p.messageID == nil
+}
+
+// isBoundary reports whether the position is the earliest or latest message, which
+// apply to every partition
+func (p startPosition) isBoundary() bool {
+	return p.messageID == pulsar.EarliestMessageID() || p.messageID == pulsar.LatestMessageID()
+}
+
+// loadTimezone resolves a --timezone value. Empty or "Local" is the local zone
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", name, err)
+	}
+	return loc, nil
+}
+
+// parseTimeBound parses an absolute or relative point in time. Accepted forms are
+// "now", RFC3339, "YYYY-MM-DD HH:MM:SS" in the given location, and negative
+// durations relative to now such as -15m or -2h
+func parseTimeBound(value string, loc *time.Location, now time.Time) (time.Time, error) {
+	if value == "now" {
+		return now, nil
+	}
+	if strings.HasPrefix(value, "-") {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time %q: %v", value, err)
+		}
+		return now.Add(d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(localTimeLayout, value, loc); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339, 'YYYY-MM-DD HH:MM:SS' or a relative duration like -15m", value)
+}
+
+// parseMessageID parses a message ID in the ledgerId:entryId[:partition[:batchIndex]]
+// form printed by the read, dump and dlq commands
+func parseMessageID(value string) (pulsar.MessageID, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 4 {
+		return nil, fmt.Errorf("invalid message ID %q, expected ledgerId:entryId[:partition[:batchIndex]]", value)
+	}
+	nums := []int64{0, 0, -1, -1}
+	for i, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message ID %q: %v", value, err)
+		}
+		nums[i] = n
+	}
+	return pulsar.NewMessageID(nums[0], nums[1], int32(nums[3]), int32(nums[2])), nil
+}
+
+// parseStartPosition parses a --from value: earliest, latest, a message ID, or any
+// form accepted by parseTimeBound. Message IDs are matched first, as their partition
+// may be negative
+func parseStartPosition(value string, loc *time.Location, now time.Time) (startPosition, error) {
+	switch value {
+	case "earliest":
+		return startPosition{messageID: pulsar.EarliestMessageID()}, nil
+	case "latest":
+		return startPosition{messageID: pulsar.LatestMessageID()}, nil
+	}
+	if messageIDPattern.MatchString(value) {
+		id, err := parseMessageID(value)
+		if err != nil {
+			return startPosition{}, err
+		}
+		return startPosition{messageID: id}, nil
+	}
+	t, err := parseTimeBound(value, loc, now)
+	if err != nil {
+		return startPosition{}, fmt.Errorf("invalid start position %q, expected earliest, latest, a message ID or a time", value)
+	}
+	return startPosition{time: t}, nil
+}
+
+// endPosition is where a read stops: after a message ID or a publish time
+type endPosition struct {
+	messageID pulsar.MessageID
+	time      time.Time
+}
+
+// parseEndPosition parses an end bound given as a message ID or a time
+func parseEndPosition(value string, loc *time.Location, now time.Time) (endPosition, error) {
+	if value == "" {
+		return endPosition{}, nil
+	}
+	start, err := parseStartPosition(value, loc, now)
+	if err != nil {
+		return endPosition{}, err
+	}
+	if value == "earliest" || value == "latest" {
+		return endPosition{}, fmt.Errorf("invalid end position %q, expected a message ID or a time", value)
+	}
+	return endPosition{messageID: start.messageID, time: start.time}, nil
+}
+
+// after reports whether the message lies beyond the end bound. A message ID bound
+// only applies to messages of its partition, as IDs of different partitions are
+// not comparable
+func (p endPosition) after(msg pulsar.Message) bool {
+	if p.messageID != nil {
+		id := msg.ID()
+		if id.PartitionIdx() != p.messageID.PartitionIdx() {
+			return false
+		}
+		if id.LedgerID() != p.messageID.LedgerID() {
+			return id.LedgerID() > p.messageID.LedgerID()
+		}
+		return id.EntryID() > p.messageID.EntryID()
+	}
+	return !p.time.IsZero() && msg.PublishTime().After(p.time)
+}
@@ -5,34 +5,83 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
 
+// maxJSONLLineSize is the largest single line accepted from a JSONL input
+const maxJSONLLineSize = 10 * 1024 * 1024
+
 var (
-	pulsarURL  string
-	topic      string
-	jsonData   string
-	num        int
-	request    bool
-	authPlugin string
-	authParams string
+	pulsarURL         string
+	topic             string
+	jsonData          string
+	produceFile       string
+	produceJSONL      string
+	produceTemplate   bool
+	produceKey        string
+	produceOrderKey   string
+	produceProperties []string
+	produceEventTime  string
+	produceDelay      time.Duration
+	produceSchemaType string
+	produceSchemaFile string
+	produceProtoMsg   string
+	num               int
+	request           bool
+	produceAuth       authFlags
 )
 
 var produceCmd = &cobra.Command{
 	Use:   "produce",
 	Short: "Produce JSON messages to a topic",
 	Long: `Produce JSON messages to a topic.
-	
+
+The payload is taken from exactly one of --json, --file or --jsonl. With --jsonl every
+non-empty line of the input is sent as a separate message. --num repeats the whole input.
+Messages that are not valid JSON, cannot be encoded or fail to send are skipped, and the
+command fails once the others are sent.
+
+With --schema-type the JSON payload is encoded with a Pulsar schema before it is sent:
+  string                    payload is sent as a UTF-8 string
+  json                      payload is validated JSON, --schema-file holds the Avro-style definition
+  avro                      payload is Avro encoded, --schema-file holds the Avro schema definition
+  protobuf                  payload is protobuf encoded (PROTOBUF_NATIVE), --schema-file holds a
+                            descriptor set from 'protoc --include_imports --descriptor_set_out'
+
+With --template the payload is rendered as a Go template before each send. Available
+placeholders:
+  {{.Seq}}                  1-based sequence number of the message in this run
+  {{.Index}}                0-based index of the payload within the input
+  {{uuid}}                  random UUID
+  {{randInt 1 100}}         random integer in [min, max]
+  {{randFloat}}             random float in [0, 1)
+  {{randString 8}}          random alphanumeric string of length n
+  {{randChoice "a" "b"}}    random choice from the arguments
+  {{now}}                   current time in RFC3339
+  {{unix}} / {{unixMillis}} current Unix time in seconds / milliseconds
+
 Examples:
   ascli produce --topic my-topic --json '{"key": "value"}'
   ascli produce --topic my-topic --json - --num 5  # Read JSON from stdin
+  ascli produce --topic my-topic --file payload.json
+  ascli produce --topic my-topic --jsonl events.jsonl --delay-between 100ms
+  cat events.jsonl | ascli produce --topic my-topic --jsonl -
+  ascli produce --topic my-topic --json '{"id": {{.Seq}}, "user": "{{randString 6}}", "ts": "{{now}}"}' --template --num 100
+  ascli produce --topic my-topic --json '{"key": "value"}' --key user-1 --property source=replay --event-time now
+  ascli produce --topic my-topic --json '{"key": "value"}' --ordering-key session-42
+  ascli produce --topic my-topic --json '{"id": 1, "name": "a"}' --schema-type avro --schema-file event.avsc
+  ascli produce --topic my-topic --json '{"id": 1}' --schema-type protobuf --schema-file event.desc --proto-message acme.Event
   ascli produce --topic my-topic --json '{"key": "value"}' --request
-  ascli produce --topic my-topic --json '{"key": "value"}' --auth-plugin org.apache.pulsar.client.impl.auth.AuthenticationTls --auth-params '{"tlsCertFile": "/path/to/cert.pem", "tlsKeyFile": "/path/to/key.pem"}'
-  
+  ascli produce --topic my-topic --json '{"key": "value"}' --tls-cert /path/to/cert.pem --tls-key /path/to/key.pem
+
 Context support:
   ascli context create local --pulsar-url pulsar://localhost:6650
   ascli context use local
@@ -45,48 +94,157 @@ func init() {
 
 	produceCmd.Flags().StringVar(&pulsarURL, "pulsar-url", "", "Service URL (overrides context)")
 	produceCmd.Flags().StringVar(&topic, "topic", "", "Topic to produce to (required)")
-	produceCmd.Flags().StringVar(&jsonData, "json", "", "JSON string to send, or '-' to read from stdin (required)")
-	produceCmd.Flags().IntVar(&num, "num", 1, "Number of messages to produce")
+	produceCmd.Flags().StringVar(&jsonData, "json", "", "JSON string to send, or '-' to read from stdin")
+	produceCmd.Flags().StringVar(&produceFile, "file", "", "File containing a single JSON document to send, or '-' to read from stdin")
+	produceCmd.Flags().StringVar(&produceJSONL, "jsonl", "", "JSON Lines file to send one message per line, or '-' to read from stdin")
+	produceCmd.Flags().BoolVar(&produceTemplate, "template", false, "Render payloads as templates with sequence, random and timestamp placeholders")
+	produceCmd.Flags().StringVar(&produceKey, "key", "", "Message key, used for partition routing and compaction")
+	produceCmd.Flags().StringVar(&produceOrderKey, "ordering-key", "", "Message ordering key, used by Key_Shared subscriptions")
+	produceCmd.Flags().StringArrayVar(&produceProperties, "property", nil, "Message property in key=value format (repeatable, a traceparent is set unless given)")
+	produceCmd.Flags().StringVar(&produceEventTime, "event-time", "", "Event time of the messages, in RFC3339 format or 'now' to use the send time")
+	produceCmd.Flags().DurationVar(&produceDelay, "delay-between", 0, "Delay between consecutive messages (e.g. 100ms, 1s)")
+	produceCmd.Flags().StringVar(&produceSchemaType, "schema-type", "", "Schema to encode payloads with: bytes, string, json, avro or protobuf (default bytes)")
+	produceCmd.Flags().StringVar(&produceSchemaFile, "schema-file", "", "Schema definition file (Avro schema for json/avro, descriptor set for protobuf)")
+	produceCmd.Flags().StringVar(&produceProtoMsg, "proto-message", "", "Fully qualified protobuf message name in the descriptor set")
+	produceCmd.Flags().IntVar(&num, "num", 1, "Number of times to send the input")
 	produceCmd.Flags().BoolVar(&request, "request", false, "Send as a request message")
-	produceCmd.Flags().StringVar(&authPlugin, "auth-plugin", "", "Authentication plugin class name (overrides context)")
-	produceCmd.Flags().StringVar(&authParams, "auth-params", "", "Authentication parameters (JSON string) (overrides context)")
+	addAuthFlags(produceCmd, &produceAuth)
 
 	produceCmd.MarkFlagRequired("topic")
-	produceCmd.MarkFlagRequired("json")
+	registerTopicCompletion(produceCmd, &pulsarURL, &produceAuth)
+	produceCmd.MarkFlagsOneRequired("json", "file", "jsonl")
+	produceCmd.MarkFlagsMutuallyExclusive("json", "file", "jsonl")
 }
 
-func runProduce(cmd *cobra.Command, args []string) error {
-	// Parse JSON data
-	var data interface{}
-	var messageStr string
-
-	if jsonData == "-" {
-		// Read from stdin
-		scanner := bufio.NewScanner(os.Stdin)
-		var input string
-		for scanner.Scan() {
-			input += scanner.Text() + "\n"
+// readInput reads all data from the given file path, or from stdin if the path is '-'
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// readJSONLines reads one payload per non-empty line from the given file path or stdin
+func readJSONLines(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
 		}
-		if err := json.Unmarshal([]byte(input), &data); err != nil {
-			return fmt.Errorf("invalid JSON data from stdin: %v", err)
+		defer f.Close()
+		r = f
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// loadProducePayloads returns the raw payloads selected by the input flags
+func loadProducePayloads() ([]string, error) {
+	switch {
+	case produceJSONL != "":
+		lines, err := readJSONLines(produceJSONL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSONL input: %v", err)
+		}
+		if len(lines) == 0 {
+			return nil, fmt.Errorf("JSONL input contains no messages")
+		}
+		return lines, nil
+	case produceFile != "":
+		data, err := readInput(produceFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %v", err)
+		}
+		return []string{string(data)}, nil
+	case jsonData == "-":
+		data, err := readInput("-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %v", err)
+		}
+		return []string{string(data)}, nil
+	default:
+		return []string{jsonData}, nil
+	}
+}
+
+// parseEventTime parses the --event-time flag. It returns a zero time if unset and
+// reports whether the send time should be used instead
+func parseEventTime(value string) (time.Time, bool, error) {
+	switch value {
+	case "":
+		return time.Time{}, false, nil
+	case "now":
+		return time.Time{}, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid event time %q, expected RFC3339 or 'now': %v", value, err)
+	}
+	return t, false, nil
+}
+
+func runProduce(cmd *cobra.Command, args []string) error {
+	payloads, err := loadProducePayloads()
+	if err != nil {
+		return err
+	}
+
+	// Parse templates up front so syntax errors are reported before connecting
+	var templates []*template.Template
+	if produceTemplate {
+		for i, payload := range payloads {
+			tmpl, err := parsePayloadTemplate(fmt.Sprintf("payload-%d", i), payload)
+			if err != nil {
+				return err
+			}
+			templates = append(templates, tmpl)
 		}
-		messageStr = input
 	} else {
-		// Parse the provided JSON string
-		if err := json.Unmarshal([]byte(jsonData), &data); err != nil {
-			return fmt.Errorf("invalid JSON data: %v", err)
+		for i, payload := range payloads {
+			var data interface{}
+			if err := json.Unmarshal([]byte(payload), &data); err != nil {
+				return fmt.Errorf("invalid JSON data in payload %d: %v", i+1, err)
+			}
 		}
-		messageStr = jsonData
+	}
+
+	baseProperties, err := parseProperties(produceProperties)
+	if err != nil {
+		return err
+	}
+
+	eventTime, eventTimeNow, err := parseEventTime(produceEventTime)
+	if err != nil {
+		return err
+	}
+
+	schema, err := loadProducerSchema(produceSchemaType, produceSchemaFile, produceProtoMsg)
+	if err != nil {
+		return err
 	}
 
 	// Get configuration from context or command line
-	url, authPlugin, authParams, err := getContextConfig(pulsarURL, authPlugin, authParams)
+	cfg, err := resolveConfig(configFlags{pulsarURL: pulsarURL, auth: produceAuth})
 	if err != nil {
 		return fmt.Errorf("failed to get context configuration: %v", err)
 	}
 
 	// Create client
-	clientOpts, err := buildClientOptions(url, authPlugin, authParams)
+	clientOpts, err := buildClientOptions(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to build client options: %v", err)
 	}
@@ -98,35 +256,92 @@ func runProduce(cmd *cobra.Command, args []string) error {
 	defer client.Close()
 
 	// Create producer
-	producer, err := client.CreateProducer(pulsar.ProducerOptions{
+	producerOpts := pulsar.ProducerOptions{
 		Topic: topic,
-	})
+	}
+	if schema != nil {
+		producerOpts.Schema = schema.schema
+	}
+	producer, err := client.CreateProducer(producerOpts)
 	if err != nil {
 		return fmt.Errorf("failed to create producer: %v", err)
 	}
 	defer producer.Close()
 
-	// Prepare properties
-	properties := make(map[string]string)
-	if request {
-		properties["request_id"] = uuid.New().String()
-	}
-
-	// Send messages
+	// Send messages. Messages that cannot be sent are skipped, and fail the command
+	// once the rest are sent
+	seq := 0
+	failed := 0
 	for i := 0; i < num; i++ {
-		msgID, err := producer.Send(context.Background(), &pulsar.ProducerMessage{
-			Payload:    []byte(messageStr),
-			Properties: properties,
-		})
+		for index, payload := range payloads {
+			seq++
+			if seq > 1 && produceDelay > 0 {
+				time.Sleep(produceDelay)
+			}
 
-		if err != nil {
-			fmt.Printf("Failed to send message %d to topic '%s': %v\n", i+1, topic, err)
-		} else {
-			fmt.Printf("Message %d sent to topic '%s' (%s):\n", i+1, topic, msgID)
-			prettyJSON, _ := json.MarshalIndent(data, "", "  ")
-			fmt.Println(string(prettyJSON))
+			messageStr := payload
+			if produceTemplate {
+				messageStr, err = renderPayloadTemplate(templates[index], templateData{Seq: seq, Index: index})
+				if err != nil {
+					return err
+				}
+			}
+
+			var data interface{}
+			if err := json.Unmarshal([]byte(messageStr), &data); err != nil {
+				fmt.Printf("Skipping message %d: rendered payload is not valid JSON: %v\n", seq, err)
+				failed++
+				continue
+			}
+
+			payloadBytes := []byte(messageStr)
+			if schema != nil {
+				payloadBytes, err = schema.encode(payloadBytes)
+				if err != nil {
+					fmt.Printf("Skipping message %d: failed to encode payload with %s schema: %v\n", seq, produceSchemaType, err)
+					failed++
+					continue
+				}
+			}
+
+			// Prepare properties
+			properties := make(map[string]string, len(baseProperties)+1)
+			for k, v := range baseProperties {
+				properties[k] = v
+			}
+			if request {
+				properties["request_id"] = uuid.New().String()
+			}
+			// Every message starts a trace of its own, unless it joins the one in TRACEPARENT
+			if _, err := setTraceparent(properties); err != nil {
+				return err
+			}
+
+			msg := &pulsar.ProducerMessage{
+				Payload:     payloadBytes,
+				Key:         produceKey,
+				OrderingKey: produceOrderKey,
+				Properties:  properties,
+				EventTime:   eventTime,
+			}
+			if eventTimeNow {
+				msg.EventTime = time.Now()
+			}
+
+			msgID, err := producer.Send(context.Background(), msg)
+			if err != nil {
+				fmt.Printf("Failed to send message %d to topic '%s': %v\n", seq, topic, err)
+				failed++
+			} else {
+				fmt.Printf("Message %d sent to topic '%s' (%s):\n", seq, topic, msgID)
+				prettyJSON, _ := json.MarshalIndent(data, "", "  ")
+				fmt.Println(string(prettyJSON))
+			}
 		}
 	}
 
+	if failed > 0 {
+		return fmt.Errorf("%d of %d messages were not sent", failed, seq)
+	}
 	return nil
 }
@@ -11,25 +11,46 @@ import (
 )
 
 var (
-	readPulsarURL  string
-	readTopic      string
-	seekTime       string
-	readNum        int
-	readAuthPlugin string
-	readAuthParams string
+	readPulsarURL string
+	readTopic     string
+	seekTime      string
+	readFrom      string
+	readUntil     string
+	readTimezone  string
+	readNum       int
+	readAuth      authFlags
+	readAdminURL  string
 )
 
 var readCmd = &cobra.Command{
 	Use:   "read",
 	Short: "Read messages from a topic",
 	Long: `Read messages from a topic.
-	
+
+The start position given by --from can be one of:
+  earliest                  the oldest available message (default)
+  latest                    only messages published after the reader starts
+  <ledgerId:entryId[:partition]>  a message ID as printed by read, dump or dlq list
+  <RFC3339>                 a point in time, e.g. 2024-01-01T12:00:00Z
+  <YYYY-MM-DD HH:MM:SS>     a point in time in the --timezone zone
+  -15m, -2h                 a duration relative to now
+
+--until accepts the same time forms and stops reading at the first message published
+after it. Partitioned topics are read from all partitions and merged by publish time.
+
+Payloads produced with an Avro, JSON or Protobuf schema are decoded to JSON using the
+schema version registered for the topic, looked up through the admin API.
+
 Examples:
   ascli read --topic my-topic
-  ascli read --topic my-topic --seek-time "2024-01-01 12:00:00"
+  ascli read --topic my-topic --from -15m
+  ascli read --topic my-topic --from "2024-01-01 12:00:00" --timezone Europe/Berlin
+  ascli read --topic my-topic --from 2024-01-01T12:00:00Z --until 2024-01-01T13:00:00Z --num 1000
+  ascli read --topic my-topic --from 123:45:0
   ascli read --topic my-topic --num 20
-  ascli read --topic my-topic --auth-plugin org.apache.pulsar.client.impl.auth.AuthenticationTls --auth-params '{"tlsCertFile": "/path/to/cert.pem", "tlsKeyFile": "/path/to/key.pem"}'
-  
+  ascli read --topic my-avro-topic --admin-url http://localhost:8080
+  ascli read --topic my-topic --tls-cert /path/to/cert.pem --tls-key /path/to/key.pem
+
 Context support:
   ascli context create local --pulsar-url pulsar://localhost:6650
   ascli context use local
@@ -42,29 +63,52 @@ func init() {
 
 	readCmd.Flags().StringVar(&readPulsarURL, "pulsar-url", "", "Service URL (overrides context)")
 	readCmd.Flags().StringVar(&readTopic, "topic", "", "Topic to read from (required)")
-	readCmd.Flags().StringVar(&seekTime, "seek-time", time.Now().Format("2006-01-02 15:04:05"), "Seek time in format YYYY-MM-DD HH:MM:SS")
+	readCmd.Flags().StringVar(&readFrom, "from", "earliest", "Start position: earliest, latest, a message ID, a time or a relative duration like -15m")
+	readCmd.Flags().StringVar(&readUntil, "until", "", "Stop at the first message published after this time (same time forms as --from)")
+	readCmd.Flags().StringVar(&readTimezone, "timezone", "Local", "Time zone used to parse times without an offset and to display publish times (e.g. UTC, Europe/Berlin)")
+	readCmd.Flags().StringVar(&seekTime, "seek-time", "", "Seek time in format YYYY-MM-DD HH:MM:SS")
 	readCmd.Flags().IntVar(&readNum, "num", 10, "Number of messages to read")
-	readCmd.Flags().StringVar(&readAuthPlugin, "auth-plugin", "", "Authentication plugin class name (overrides context)")
-	readCmd.Flags().StringVar(&readAuthParams, "auth-params", "", "Authentication parameters (JSON string) (overrides context)")
+	addAuthFlags(readCmd, &readAuth)
+	readCmd.Flags().StringVar(&readAdminURL, "admin-url", "", "Admin service URL used to look up schemas (derived from the service URL if not specified)")
 
+	readCmd.Flags().MarkDeprecated("seek-time", "use --from instead")
+	readCmd.MarkFlagsMutuallyExclusive("from", "seek-time")
 	readCmd.MarkFlagRequired("topic")
+	registerTopicCompletion(readCmd, &readPulsarURL, &readAuth)
 }
 
 func runRead(cmd *cobra.Command, args []string) error {
-	// Parse seek time
-	seekTimeParsed, err := time.Parse("2006-01-02 15:04:05", seekTime)
+	loc, err := loadTimezone(readTimezone)
 	if err != nil {
-		return fmt.Errorf("invalid seek_time format. Use YYYY-MM-DD HH:MM:SS: %v", err)
+		return err
+	}
+
+	from := readFrom
+	if seekTime != "" {
+		from = seekTime
+	}
+	now := time.Now()
+	start, err := parseStartPosition(from, loc, now)
+	if err != nil {
+		return err
+	}
+
+	var until time.Time
+	if readUntil != "" {
+		until, err = parseTimeBound(readUntil, loc, now)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Get configuration from context or command line
-	url, authPlugin, authParams, err := getContextConfig(readPulsarURL, readAuthPlugin, readAuthParams)
+	cfg, err := resolveConfig(configFlags{pulsarURL: readPulsarURL, adminURL: readAdminURL, auth: readAuth})
 	if err != nil {
 		return fmt.Errorf("failed to get context configuration: %v", err)
 	}
 
 	// Create client
-	clientOpts, err := buildClientOptions(url, authPlugin, authParams)
+	clientOpts, err := buildClientOptions(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to build client options: %v", err)
 	}
@@ -75,21 +119,18 @@ func runRead(cmd *cobra.Command, args []string) error {
 	}
 	defer client.Close()
 
-	// Create reader
-	reader, err := client.CreateReader(pulsar.ReaderOptions{
-		Topic:          readTopic,
-		StartMessageID: pulsar.EarliestMessageID(),
-	})
+	admin, err := buildAdminClient(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create reader: %v", err)
+		return err
 	}
-	defer reader.Close()
+	decoder := newSchemaDecoder(admin, readTopic)
 
-	// Seek to the specified time
-	err = reader.SeekByTime(seekTimeParsed)
+	// Create reader positioned at the start of every partition
+	reader, err := newTopicReader(client, readTopic, start)
 	if err != nil {
-		return fmt.Errorf("failed to seek to time: %v", err)
+		return err
 	}
+	defer reader.Close()
 
 	// Read messages
 	recvNum := 0
@@ -106,12 +147,39 @@ func runRead(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to read message: %v", err)
 		}
 
+		if !until.IsZero() && msg.PublishTime().After(until) {
+			fmt.Printf("Reached end bound after reading %d messages\n", recvNum)
+			break
+		}
+
 		rawData := string(msg.Payload())
 		fmt.Println("---")
 		fmt.Printf("Read Index: %d\n", recvNum)
 		fmt.Printf("Message ID: %s\n", msg.ID())
+		if len(reader.readers) > 1 {
+			fmt.Printf("Topic: %s\n", msg.Topic())
+		}
+		if msg.Key() != "" {
+			fmt.Printf("Key: %s\n", msg.Key())
+		}
+		if msg.OrderingKey() != "" {
+			fmt.Printf("Ordering key: %s\n", msg.OrderingKey())
+		}
 		fmt.Printf("Message Header: %v\n", msg.Properties())
-		fmt.Printf("Publish time: %s\n", msg.PublishTime().Format("2006-01-02 15:04:05"))
+		fmt.Printf("Publish time: %s\n", msg.PublishTime().In(loc).Format("2006-01-02 15:04:05 MST"))
+
+		// Decode with the registered schema if the message has one
+		value, schemaType, ok, err := decoder.decode(msg)
+		if err != nil {
+			fmt.Printf("Failed to decode payload with schema: %v\n", err)
+		}
+		if ok {
+			fmt.Printf("Schema: %s\n", schemaType)
+			prettyJSON, _ := json.MarshalIndent(value, "", "  ")
+			fmt.Println(string(prettyJSON))
+			recvNum++
+			continue
+		}
 
 		// Try to parse as JSON for pretty printing
 		var data interface{}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayPulsarURL     string
+	replayFile          string
+	replayFormat        string
+	replayTopic         string
+	replayRate          float64
+	replaySetProperties []string
+	replayDropProps     []string
+	replayKeepEventTime bool
+	replayAuth          authFlags
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Re-publish messages from a dump file to a topic",
+	Long: `Re-publish messages captured with 'ascli dump' to a topic.
+
+Payloads are sent byte for byte with their original key, ordering key and
+properties, in the order they were dumped. Properties can be overridden with
+--set-property or removed with --drop-property, for example to drop a stale
+response_topic. The original event time is kept only with --keep-event-time.
+
+Examples:
+  ascli replay --file incident.jsonl --topic staging-agent-requests
+  ascli replay --file incident.tar --topic staging-agent-requests --rate 10
+  ascli replay --file incident.jsonl --topic test-topic --drop-property response_topic --set-property replayed=true`,
+	RunE: runReplay,
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().StringVar(&replayPulsarURL, "pulsar-url", "", "Service URL (overrides context)")
+	replayCmd.Flags().StringVar(&replayFile, "file", "", "Dump file to replay, or '-' for stdin (required)")
+	replayCmd.Flags().StringVar(&replayFormat, "format", "", "Dump format: jsonl or tar (derived from the file extension if not specified)")
+	replayCmd.Flags().StringVar(&replayTopic, "topic", "", "Topic to publish to (required)")
+	replayCmd.Flags().Float64Var(&replayRate, "rate", 0, "Maximum messages per second (0 for no limit)")
+	replayCmd.Flags().StringArrayVar(&replaySetProperties, "set-property", nil, "Set a message property as key=value, overriding the dumped value (repeatable)")
+	replayCmd.Flags().StringArrayVar(&replayDropProps, "drop-property", nil, "Remove a dumped message property (repeatable)")
+	replayCmd.Flags().BoolVar(&replayKeepEventTime, "keep-event-time", false, "Send messages with their original event time")
+	addAuthFlags(replayCmd, &replayAuth)
+
+	replayCmd.MarkFlagRequired("file")
+	replayCmd.MarkFlagRequired("topic")
+	registerTopicCompletion(replayCmd, &replayPulsarURL, &replayAuth)
+}
+
+// replayProperties applies the --drop-property and --set-property overrides to the
+// dumped properties
+func replayProperties(dumped map[string]string, drop []string, set map[string]string) map[string]string {
+	properties := make(map[string]string, len(dumped)+len(set))
+	for k, v := range dumped {
+		properties[k] = v
+	}
+	for _, k := range drop {
+		delete(properties, k)
+	}
+	for k, v := range set {
+		properties[k] = v
+	}
+	return properties
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	if replayRate < 0 {
+		return fmt.Errorf("--rate must not be negative")
+	}
+
+	setProperties, err := parseProperties(replaySetProperties)
+	if err != nil {
+		return err
+	}
+
+	records, err := readDumpFile(replayFile, replayFormat)
+	if err != nil {
+		return fmt.Errorf("failed to read dump file: %v", err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("dump file '%s' contains no messages", replayFile)
+	}
+
+	// Get configuration from context or command line
+	cfg, err := resolveConfig(configFlags{pulsarURL: replayPulsarURL, auth: replayAuth})
+	if err != nil {
+		return fmt.Errorf("failed to get context configuration: %v", err)
+	}
+
+	// Create client
+	clientOpts, err := buildClientOptions(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build client options: %v", err)
+	}
+
+	client, err := pulsar.NewClient(clientOpts)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	producer, err := client.CreateProducer(pulsar.ProducerOptions{
+		Topic: replayTopic,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create producer: %v", err)
+	}
+	defer producer.Close()
+
+	var interval time.Duration
+	if replayRate > 0 {
+		interval = time.Duration(float64(time.Second) / replayRate)
+	}
+
+	sent := 0
+	next := time.Now()
+	for i, record := range records {
+		if interval > 0 {
+			time.Sleep(time.Until(next))
+			next = next.Add(interval)
+		}
+
+		msg := &pulsar.ProducerMessage{
+			Payload:     record.Payload,
+			Key:         record.Key,
+			OrderingKey: record.OrderingKey,
+			Properties:  replayProperties(record.Properties, replayDropProps, setProperties),
+		}
+		if replayKeepEventTime && record.EventTime != nil {
+			msg.EventTime = *record.EventTime
+		}
+
+		msgID, err := producer.Send(context.Background(), msg)
+		if err != nil {
+			fmt.Printf("Failed to replay message %d (%s): %v\n", i+1, record.MessageID, err)
+			continue
+		}
+		sent++
+		fmt.Printf("Message %d (%s) replayed to topic '%s' (%s)\n", i+1, record.MessageID, replayTopic, msgID)
+	}
+
+	fmt.Fprintf(os.Stderr, "Replayed %d of %d messages to topic '%s'\n", sent, len(records), replayTopic)
+	return nil
+}
@@ -1,11 +1,9 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
@@ -18,8 +16,12 @@ var (
 	rpcTopic      string
 	rpcJSON       string
 	responseTopic string
-	rpcAuthPlugin string
-	rpcAuthParams string
+	rpcAuth       authFlags
+	rpcKey        string
+	rpcOrderKey   string
+	rpcProperties []string
+	rpcTimeout    time.Duration
+	rpcTrace      bool
 )
 
 var rpcCmd = &cobra.Command{
@@ -30,7 +32,10 @@ var rpcCmd = &cobra.Command{
 Examples:
   ascli rpc --topic my-topic --json '{"key": "value"}'
   ascli rpc --topic my-topic --json -  # Read JSON from stdin
-  ascli rpc --topic my-topic --json '{"key": "value"}' --auth-plugin org.apache.pulsar.client.impl.auth.AuthenticationTls --auth-params '{"tlsCertFile": "/path/to/cert.pem", "tlsKeyFile": "/path/to/key.pem"}'
+  ascli rpc --topic my-topic --json '{"key": "value"}' --key user-1 --property tenant=acme
+  ascli rpc --topic my-topic --json '{"key": "value"}' --ordering-key session-42
+  ascli rpc --topic my-topic --json '{"key": "value"}' --trace
+  ascli rpc --topic my-topic --json '{"key": "value"}' --tls-cert /path/to/cert.pem --tls-key /path/to/key.pem
   
 Context support:
   ascli context create local --pulsar-url pulsar://localhost:6650
@@ -46,127 +51,203 @@ func init() {
 	rpcCmd.Flags().StringVar(&rpcTopic, "topic", "", "Topic to send request to (required)")
 	rpcCmd.Flags().StringVar(&rpcJSON, "json", "", "JSON string to send, or '-' to read from stdin (required)")
 	rpcCmd.Flags().StringVar(&responseTopic, "response-topic", "", "Response topic (auto-generated if not specified)")
-	rpcCmd.Flags().StringVar(&rpcAuthPlugin, "auth-plugin", "", "Authentication plugin class name (overrides context)")
-	rpcCmd.Flags().StringVar(&rpcAuthParams, "auth-params", "", "Authentication parameters (JSON string) (overrides context)")
+	addAuthFlags(rpcCmd, &rpcAuth)
+	rpcCmd.Flags().StringVar(&rpcKey, "key", "", "Message key, used for partition routing and compaction")
+	rpcCmd.Flags().StringVar(&rpcOrderKey, "ordering-key", "", "Message ordering key, used by Key_Shared subscriptions")
+	rpcCmd.Flags().StringArrayVar(&rpcProperties, "property", nil, "Message property in key=value format (repeatable, request_id and response_topic are set automatically, traceparent unless given)")
+
+	rpcCmd.Flags().DurationVar(&rpcTimeout, "timeout", 30*time.Second, "Maximum time to wait for the response")
+	rpcCmd.Flags().BoolVar(&rpcTrace, "trace", false, "Print the trace ID of the request, to look it up in the tracing backend")
 
 	rpcCmd.MarkFlagRequired("topic")
+	registerTopicCompletion(rpcCmd, &rpcPulsarURL, &rpcAuth)
 	rpcCmd.MarkFlagRequired("json")
 }
 
-func runRPC(cmd *cobra.Command, args []string) error {
-	// Parse JSON data
-	var data interface{}
-	var messageStr string
-
-	if rpcJSON == "-" {
-		// Read from stdin
-		scanner := bufio.NewScanner(os.Stdin)
-		var input string
-		for scanner.Scan() {
-			input += scanner.Text() + "\n"
-		}
-		if err := json.Unmarshal([]byte(input), &data); err != nil {
-			return fmt.Errorf("invalid JSON data from stdin: %v", err)
-		}
-		messageStr = input
-	} else {
-		// Parse the provided JSON string
-		if err := json.Unmarshal([]byte(rpcJSON), &data); err != nil {
-			return fmt.Errorf("invalid JSON data: %v", err)
-		}
-		messageStr = rpcJSON
-	}
+// rpcRequest is a request sent by callRPC
+type rpcRequest struct {
+	topic         string
+	responseTopic string
+	payload       []byte
+	key           string
+	orderingKey   string
+	properties    map[string]string
+	// printTrace prints the trace ID the request is sent with
+	printTrace bool
+}
 
-	// Generate response topic if not provided
-	if responseTopic == "" {
-		responseTopic = fmt.Sprintf("non-persistent://public/default/response-%s", uuid.New().String())
+// readJSONInput returns the raw and parsed JSON of a --json value, reading stdin if it is '-'
+func readJSONInput(value string) (string, interface{}, error) {
+	messageStr := value
+	if value == "-" {
+		input, err := readInput("-")
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read stdin: %v", err)
+		}
+		messageStr = string(input)
 	}
 
-	// Get configuration from context or command line
-	url, authPlugin, authParams, err := getContextConfig(rpcPulsarURL, rpcAuthPlugin, rpcAuthParams)
-	if err != nil {
-		return fmt.Errorf("failed to get context configuration: %v", err)
+	var data interface{}
+	if err := json.Unmarshal([]byte(messageStr), &data); err != nil {
+		if value == "-" {
+			return "", nil, fmt.Errorf("invalid JSON data from stdin: %v", err)
+		}
+		return "", nil, fmt.Errorf("invalid JSON data: %v", err)
 	}
+	return messageStr, data, nil
+}
 
-	// Create client
-	clientOpts, err := buildClientOptions(url, authPlugin, authParams)
+// parseRPCProperties parses user properties, rejecting the ones set by callRPC
+func parseRPCProperties(pairs []string) (map[string]string, error) {
+	properties, err := parseProperties(pairs)
 	if err != nil {
-		return fmt.Errorf("failed to build client options: %v", err)
+		return nil, err
 	}
-
-	client, err := pulsar.NewClient(clientOpts)
-	if err != nil {
-		return fmt.Errorf("failed to create client: %v", err)
+	for _, reserved := range []string{"request_id", "response_topic"} {
+		if _, ok := properties[reserved]; ok {
+			return nil, fmt.Errorf("property '%s' is set automatically and cannot be overridden", reserved)
+		}
 	}
-	defer client.Close()
+	return properties, nil
+}
 
+// callRPC sends a request with a new request_id and a traceparent, and waits for the
+// response carrying the same request_id on the response topic. Other messages on the
+// topic are skipped
+func callRPC(client pulsar.Client, req rpcRequest, timeout time.Duration) (pulsar.Message, error) {
 	// Create producer for request
 	producer, err := client.CreateProducer(pulsar.ProducerOptions{
-		Topic: rpcTopic,
+		Topic: req.topic,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create producer: %v", err)
+		return nil, fmt.Errorf("failed to create producer: %v", err)
 	}
 	defer producer.Close()
 
-	// Create consumer for response
+	// Create consumer for response before sending, so the response cannot be missed
 	consumer, err := client.Subscribe(pulsar.ConsumerOptions{
-		Topic:            responseTopic,
+		Topic:            req.responseTopic,
 		SubscriptionName: fmt.Sprintf("rpc-consumer-%s", uuid.New().String()),
 		Type:             pulsar.Exclusive,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create consumer: %v", err)
+		return nil, fmt.Errorf("failed to create consumer: %v", err)
 	}
 	defer consumer.Close()
 
 	// Generate request ID
 	requestID := uuid.New().String()
 
-	// Send request
-	properties := map[string]string{
-		"request_id":     requestID,
-		"response_topic": responseTopic,
+	properties := make(map[string]string, len(req.properties)+2)
+	for k, v := range req.properties {
+		properties[k] = v
+	}
+	properties["request_id"] = requestID
+	properties["response_topic"] = req.responseTopic
+	traceID, err := setTraceparent(properties)
+	if err != nil {
+		return nil, err
 	}
 
 	msgID, err := producer.Send(context.Background(), &pulsar.ProducerMessage{
-		Payload:    []byte(messageStr),
-		Properties: properties,
+		Payload:     req.payload,
+		Key:         req.key,
+		OrderingKey: req.orderingKey,
+		Properties:  properties,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to send request: %v", err)
+		return nil, fmt.Errorf("failed to send request: %v", err)
 	}
 
-	fmt.Printf("Request sent to topic '%s' (%s) with request_id: %s\n", rpcTopic, msgID, requestID)
-	fmt.Printf("Waiting for response on topic: %s\n", responseTopic)
+	fmt.Printf("Request sent to topic '%s' (%s) with request_id: %s\n", req.topic, msgID, requestID)
+	if req.printTrace {
+		fmt.Printf("Trace ID: %s\n", traceID)
+	}
+	fmt.Printf("Waiting for response on topic: %s\n", req.responseTopic)
 
 	// Wait for response
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	msg, err := consumer.Receive(ctx)
+	for {
+		msg, err := consumer.Receive(ctx)
+		if err != nil {
+			if err == context.DeadlineExceeded {
+				return nil, fmt.Errorf("no response received within %s", timeout)
+			}
+			return nil, fmt.Errorf("failed to receive response: %v", err)
+		}
+		consumer.Ack(msg)
+
+		// Check if this is the response for our request
+		if msg.Properties()["request_id"] == requestID {
+			return msg, nil
+		}
+	}
+}
+
+// printRPCResponse prints a response payload, pretty-printing it if it is JSON
+func printRPCResponse(msg pulsar.Message) {
+	rawData := string(msg.Payload())
+	fmt.Println("Received response:")
+
+	// Try to parse as JSON for pretty printing
+	var responseData interface{}
+	if err := json.Unmarshal([]byte(rawData), &responseData); err == nil {
+		prettyJSON, _ := json.MarshalIndent(responseData, "", "  ")
+		fmt.Println(string(prettyJSON))
+	} else {
+		fmt.Println(rawData)
+	}
+}
+
+func runRPC(cmd *cobra.Command, args []string) error {
+	messageStr, _, err := readJSONInput(rpcJSON)
 	if err != nil {
-		return fmt.Errorf("failed to receive response: %v", err)
+		return err
 	}
 
-	// Check if this is the response for our request
-	if msg.Properties()["request_id"] == requestID {
-		rawData := string(msg.Payload())
-		fmt.Println("Received response:")
+	properties, err := parseRPCProperties(rpcProperties)
+	if err != nil {
+		return err
+	}
 
-		// Try to parse as JSON for pretty printing
-		var responseData interface{}
-		if err := json.Unmarshal([]byte(rawData), &responseData); err == nil {
-			prettyJSON, _ := json.MarshalIndent(responseData, "", "  ")
-			fmt.Println(string(prettyJSON))
-		} else {
-			fmt.Println(rawData)
-		}
+	// Generate response topic if not provided
+	if responseTopic == "" {
+		responseTopic = fmt.Sprintf("non-persistent://public/default/response-%s", uuid.New().String())
+	}
 
-		consumer.Ack(msg)
-	} else {
-		return fmt.Errorf("received response with different request_id")
+	// Get configuration from context or command line
+	cfg, err := resolveConfig(configFlags{pulsarURL: rpcPulsarURL, auth: rpcAuth})
+	if err != nil {
+		return fmt.Errorf("failed to get context configuration: %v", err)
+	}
+
+	// Create client
+	clientOpts, err := buildClientOptions(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build client options: %v", err)
+	}
+
+	client, err := pulsar.NewClient(clientOpts)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	msg, err := callRPC(client, rpcRequest{
+		topic:         rpcTopic,
+		responseTopic: responseTopic,
+		payload:       []byte(messageStr),
+		key:           rpcKey,
+		orderingKey:   rpcOrderKey,
+		properties:    properties,
+		printTrace:    rpcTrace,
+	}, rpcTimeout)
+	if err != nil {
+		return err
 	}
 
+	printRPCResponse(msg)
 	return nil
 }
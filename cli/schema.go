@@ -0,0 +1,519 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/apache/pulsar-client-go/pulsaradmin"
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/utils"
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protobufParsingInfoProperty is the schema property the Java client uses to record
+// protobuf field numbers for PROTOBUF schemas
+const protobufParsingInfoProperty = "__PARSING_INFO__"
+
+// producerSchema couples a Pulsar schema with an encoder from JSON payloads
+type producerSchema struct {
+	schema pulsar.Schema
+	encode func(payload []byte) ([]byte, error)
+}
+
+// loadProducerSchema builds the schema used to produce JSON payloads to a topic.
+// For avro and json the schema file holds an Avro schema definition, for protobuf
+// it holds a FileDescriptorSet (protoc --include_imports --descriptor_set_out)
+func loadProducerSchema(schemaType, schemaFile, protoMessage string) (*producerSchema, error) {
+	var definition []byte
+	if schemaFile != "" {
+		data, err := os.ReadFile(schemaFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema file: %v", err)
+		}
+		definition = data
+	}
+
+	switch strings.ToLower(schemaType) {
+	case "", "bytes", "none":
+		return nil, nil
+	case "string":
+		return &producerSchema{
+			schema: pulsar.NewStringSchema(nil),
+			encode: func(payload []byte) ([]byte, error) { return payload, nil },
+		}, nil
+	case "json":
+		if definition == nil {
+			return nil, fmt.Errorf("--schema-file is required for schema type json")
+		}
+		s, err := pulsar.NewJSONSchemaWithValidation(string(definition), nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON schema definition: %v", err)
+		}
+		return &producerSchema{
+			schema: s,
+			encode: func(payload []byte) ([]byte, error) {
+				return s.Encode(json.RawMessage(payload))
+			},
+		}, nil
+	case "avro":
+		if definition == nil {
+			return nil, fmt.Errorf("--schema-file is required for schema type avro")
+		}
+		s, err := pulsar.NewAvroSchemaWithValidation(string(definition), nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Avro schema definition: %v", err)
+		}
+		return &producerSchema{
+			schema: s,
+			encode: func(payload []byte) ([]byte, error) {
+				var data interface{}
+				if err := json.Unmarshal(payload, &data); err != nil {
+					return nil, err
+				}
+				native, err := jsonToAvro(s.Codec, data)
+				if err != nil {
+					return nil, err
+				}
+				return s.Encode(native)
+			},
+		}, nil
+	case "protobuf", "protobuf-native", "protobuf_native":
+		if definition == nil {
+			return nil, fmt.Errorf("--schema-file is required for schema type protobuf")
+		}
+		desc, err := findProtoMessage(definition, protoMessage)
+		if err != nil {
+			return nil, err
+		}
+		s := pulsar.NewProtoNativeSchemaWithMessage(dynamicpb.NewMessage(desc), nil)
+		return &producerSchema{
+			schema: s,
+			encode: func(payload []byte) ([]byte, error) {
+				msg := dynamicpb.NewMessage(desc)
+				if err := protojson.Unmarshal(payload, msg); err != nil {
+					return nil, err
+				}
+				return proto.Marshal(msg)
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported schema type %q, expected one of: bytes, string, json, avro, protobuf", schemaType)
+	}
+}
+
+// findProtoMessage resolves a message descriptor from a serialized FileDescriptorSet.
+// If name is empty the set must declare exactly one message
+func findProtoMessage(descriptorSet []byte, name string) (protoreflect.MessageDescriptor, error) {
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(descriptorSet, &fds); err != nil {
+		return nil, fmt.Errorf("invalid protobuf descriptor set: %v", err)
+	}
+	files, err := protodesc.NewFiles(&fds)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protobuf descriptor set: %v", err)
+	}
+
+	if name != "" {
+		d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, fmt.Errorf("message %s not found in descriptor set: %v", name, err)
+		}
+		md, ok := d.(protoreflect.MessageDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a message", name)
+		}
+		return md, nil
+	}
+
+	var candidates []protoreflect.MessageDescriptor
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		msgs := fd.Messages()
+		for i := 0; i < msgs.Len(); i++ {
+			candidates = append(candidates, msgs.Get(i))
+		}
+		return true
+	})
+	if len(candidates) != 1 {
+		return nil, fmt.Errorf("descriptor set declares %d messages, use --proto-message to select one", len(candidates))
+	}
+	return candidates[0], nil
+}
+
+// jsonToAvro converts a decoded JSON value into the native Go types the Avro
+// encoder expects for the given schema. Union values may be given either bare or
+// wrapped in the Avro JSON encoding form {"<type>": value}
+func jsonToAvro(schema avro.Schema, value interface{}) (interface{}, error) {
+	switch s := schema.(type) {
+	case *avro.RefSchema:
+		return jsonToAvro(s.Schema(), value)
+	case *avro.RecordSchema:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object for record %s, got %T", s.FullName(), value)
+		}
+		record := make(map[string]interface{}, len(s.Fields()))
+		for _, field := range s.Fields() {
+			v, ok := obj[field.Name()]
+			if !ok {
+				if field.HasDefault() {
+					continue
+				}
+				v = nil
+			}
+			converted, err := jsonToAvro(field.Type(), v)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", s.FullName(), field.Name(), err)
+			}
+			record[field.Name()] = converted
+		}
+		return record, nil
+	case *avro.ArraySchema:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array, got %T", value)
+		}
+		result := make([]interface{}, len(items))
+		for i, item := range items {
+			converted, err := jsonToAvro(s.Items(), item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			result[i] = converted
+		}
+		return result, nil
+	case *avro.MapSchema:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object for map, got %T", value)
+		}
+		result := make(map[string]interface{}, len(obj))
+		for k, v := range obj {
+			converted, err := jsonToAvro(s.Values(), v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", k, err)
+			}
+			result[k] = converted
+		}
+		return result, nil
+	case *avro.UnionSchema:
+		return jsonToAvroUnion(s, value)
+	case *avro.EnumSchema:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string for enum %s, got %T", s.FullName(), value)
+		}
+		return str, nil
+	case *avro.FixedSchema:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string for fixed %s, got %T", s.FullName(), value)
+		}
+		b := []byte(str)
+		if len(b) != s.Size() {
+			return nil, fmt.Errorf("fixed %s requires %d bytes, got %d", s.FullName(), s.Size(), len(b))
+		}
+		arr := make([]byte, s.Size())
+		copy(arr, b)
+		return arr, nil
+	case *avro.PrimitiveSchema:
+		return jsonToAvroPrimitive(s.Type(), value)
+	}
+	return value, nil
+}
+
+func jsonToAvroPrimitive(typ avro.Type, value interface{}) (interface{}, error) {
+	switch typ {
+	case avro.Null:
+		if value != nil {
+			return nil, fmt.Errorf("expected null, got %T", value)
+		}
+		return nil, nil
+	case avro.Boolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected boolean, got %T", value)
+		}
+		return b, nil
+	case avro.Int, avro.Long, avro.Float, avro.Double:
+		n, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("expected number for %s, got %T", typ, value)
+		}
+		switch typ {
+		case avro.Int:
+			return int(n), nil
+		case avro.Long:
+			return int64(n), nil
+		case avro.Float:
+			return float32(n), nil
+		}
+		return n, nil
+	case avro.String:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		return str, nil
+	case avro.Bytes:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string for bytes, got %T", value)
+		}
+		return []byte(str), nil
+	}
+	return value, nil
+}
+
+func jsonToAvroUnion(s *avro.UnionSchema, value interface{}) (interface{}, error) {
+	if value == nil {
+		for _, t := range s.Types() {
+			if t.Type() == avro.Null {
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("null is not allowed by union %s", s.String())
+	}
+
+	// Avro JSON encoding wraps union values as {"<type name>": value}
+	if obj, ok := value.(map[string]interface{}); ok && len(obj) == 1 {
+		for name, inner := range obj {
+			for _, t := range s.Types() {
+				if avroTypeName(t) == name {
+					converted, err := jsonToAvro(t, inner)
+					if err != nil {
+						return nil, err
+					}
+					return map[string]interface{}{name: converted}, nil
+				}
+			}
+		}
+	}
+
+	for _, t := range s.Types() {
+		if t.Type() == avro.Null {
+			continue
+		}
+		if converted, err := jsonToAvro(t, value); err == nil {
+			return map[string]interface{}{avroTypeName(t): converted}, nil
+		}
+	}
+	return nil, fmt.Errorf("value %v does not match any type of union %s", value, s.String())
+}
+
+// avroTypeName returns the name that identifies a schema within a union
+func avroTypeName(schema avro.Schema) string {
+	if named, ok := schema.(avro.NamedSchema); ok {
+		return named.FullName()
+	}
+	if ref, ok := schema.(*avro.RefSchema); ok {
+		return ref.Schema().FullName()
+	}
+	return string(schema.Type())
+}
+
+// schemaDecoder decodes schema-encoded message payloads for display. Schemas are
+// looked up through the admin API by the version stamped on each message
+type schemaDecoder struct {
+	admin pulsaradmin.Client
+	topic string
+
+	mu    sync.Mutex
+	cache map[int64]*utils.SchemaInfo
+}
+
+func newSchemaDecoder(admin pulsaradmin.Client, topic string) *schemaDecoder {
+	return &schemaDecoder{
+		admin: admin,
+		topic: topic,
+		cache: make(map[int64]*utils.SchemaInfo),
+	}
+}
+
+// schemaInfo returns the schema registered for the given message schema version
+func (d *schemaDecoder) schemaInfo(schemaVersion []byte) (*utils.SchemaInfo, error) {
+	if len(schemaVersion) != 8 {
+		return nil, fmt.Errorf("unsupported schema version %x", schemaVersion)
+	}
+	version := int64(binary.BigEndian.Uint64(schemaVersion))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if info, ok := d.cache[version]; ok {
+		return info, nil
+	}
+	info, err := d.admin.Schemas().GetSchemaInfoByVersion(d.topic, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema version %d: %v", version, err)
+	}
+	d.cache[version] = info
+	return info, nil
+}
+
+// decode returns the message payload decoded to a JSON-compatible value, and the
+// schema type used. Messages without a schema version yield ok=false
+func (d *schemaDecoder) decode(msg pulsar.Message) (value interface{}, schemaType string, ok bool, err error) {
+	if d == nil || len(msg.SchemaVersion()) == 0 {
+		return nil, "", false, nil
+	}
+	info, err := d.schemaInfo(msg.SchemaVersion())
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	schemaType = strings.ToUpper(info.Type)
+	switch schemaType {
+	case "AVRO":
+		codec, err := avro.Parse(string(info.Schema))
+		if err != nil {
+			return nil, schemaType, false, fmt.Errorf("invalid Avro schema: %v", err)
+		}
+		if err := avro.Unmarshal(codec, msg.Payload(), &value); err != nil {
+			return nil, schemaType, false, fmt.Errorf("failed to decode Avro payload: %v", err)
+		}
+		return value, schemaType, true, nil
+	case "JSON":
+		if err := json.Unmarshal(msg.Payload(), &value); err != nil {
+			return nil, schemaType, false, fmt.Errorf("failed to decode JSON payload: %v", err)
+		}
+		return value, schemaType, true, nil
+	case "PROTOBUF_NATIVE":
+		value, err = decodeProtoNative(info.Schema, msg.Payload())
+		if err != nil {
+			return nil, schemaType, false, err
+		}
+		return value, schemaType, true, nil
+	case "PROTOBUF":
+		value, err = decodeProtoWire(msg.Payload(), protobufFieldNames(info.Properties))
+		if err != nil {
+			return nil, schemaType, false, err
+		}
+		return value, schemaType, true, nil
+	}
+	return nil, schemaType, false, nil
+}
+
+// protoNativeSchemaData mirrors the definition stored for PROTOBUF_NATIVE schemas
+type protoNativeSchemaData struct {
+	FileDescriptorSet      []byte `json:"fileDescriptorSet"`
+	RootMessageTypeName    string `json:"rootMessageTypeName"`
+	RootFileDescriptorName string `json:"rootFileDescriptorName"`
+}
+
+func decodeProtoNative(definition, payload []byte) (interface{}, error) {
+	var data protoNativeSchemaData
+	if err := json.Unmarshal(definition, &data); err != nil {
+		return nil, fmt.Errorf("invalid protobuf native schema: %v", err)
+	}
+	desc, err := findProtoMessage(data.FileDescriptorSet, data.RootMessageTypeName)
+	if err != nil {
+		return nil, err
+	}
+	msg := dynamicpb.NewMessage(desc)
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode protobuf payload: %v", err)
+	}
+	out, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(out, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// protobufFieldNames returns the top-level field names recorded by the Java client
+// for PROTOBUF schemas, keyed by field number
+func protobufFieldNames(properties map[string]string) map[protowire.Number]string {
+	var parsingInfo []struct {
+		Number int32  `json:"number"`
+		Name   string `json:"name"`
+	}
+	names := make(map[protowire.Number]string)
+	if err := json.Unmarshal([]byte(properties[protobufParsingInfoProperty]), &parsingInfo); err != nil {
+		return names
+	}
+	for _, info := range parsingInfo {
+		names[protowire.Number(info.Number)] = info.Name
+	}
+	return names
+}
+
+// decodeProtoWire decodes a protobuf payload without its descriptor. Fields are
+// named from names where known and by number otherwise; length-delimited fields
+// are shown as strings when they are valid UTF-8 and base64 otherwise
+func decodeProtoWire(payload []byte, names map[protowire.Number]string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for len(payload) > 0 {
+		num, typ, n := protowire.ConsumeTag(payload)
+		if n < 0 {
+			return nil, fmt.Errorf("failed to decode protobuf payload: %v", protowire.ParseError(n))
+		}
+		payload = payload[n:]
+
+		var value interface{}
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(payload)
+			if n < 0 {
+				return nil, fmt.Errorf("failed to decode protobuf payload: %v", protowire.ParseError(n))
+			}
+			value, payload = v, payload[n:]
+		case protowire.Fixed32Type:
+			v, n := protowire.ConsumeFixed32(payload)
+			if n < 0 {
+				return nil, fmt.Errorf("failed to decode protobuf payload: %v", protowire.ParseError(n))
+			}
+			value, payload = v, payload[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(payload)
+			if n < 0 {
+				return nil, fmt.Errorf("failed to decode protobuf payload: %v", protowire.ParseError(n))
+			}
+			value, payload = v, payload[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(payload)
+			if n < 0 {
+				return nil, fmt.Errorf("failed to decode protobuf payload: %v", protowire.ParseError(n))
+			}
+			if utf8.Valid(v) {
+				value = string(v)
+			} else {
+				value = base64.StdEncoding.EncodeToString(v)
+			}
+			payload = payload[n:]
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", typ)
+		}
+
+		name, ok := names[num]
+		if !ok {
+			name = fmt.Sprintf("%d", num)
+		}
+		// Repeated fields are collected into a list
+		if existing, ok := result[name]; ok {
+			if list, ok := existing.([]interface{}); ok {
+				result[name] = append(list, value)
+			} else {
+				result[name] = []interface{}{existing, value}
+			}
+		} else {
+			result[name] = value
+		}
+	}
+	return result, nil
+}
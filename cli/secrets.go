@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/99designs/keyring"
+)
+
+const (
+	// secretStoreKeyring keeps context secrets in the OS keyring
+	secretStoreKeyring = "keyring"
+	// secretStoreFile keeps context secrets in a file only readable by the user
+	secretStoreFile = "file"
+
+	// secretServiceName is the keyring service the secrets are stored under
+	secretServiceName = "ascli"
+)
+
+// contextSecrets holds the sensitive settings of a context, which are kept out of
+// the contexts file
+type contextSecrets struct {
+	AuthParams         string `json:"auth_params,omitempty"`
+	Token              string `json:"token,omitempty"`
+	OAuth2ClientSecret string `json:"oauth2_client_secret,omitempty"`
+}
+
+// isEmpty reports whether there are no secrets to store
+func (s contextSecrets) isEmpty() bool {
+	return s == contextSecrets{}
+}
+
+// secretStore stores the secrets of contexts by context name
+type secretStore interface {
+	get(name string) (contextSecrets, error)
+	set(name string, secrets contextSecrets) error
+	remove(name string) error
+}
+
+// keyringStore stores secrets in the OS keyring (macOS Keychain, Secret Service,
+// KWallet or Windows Credential Manager)
+type keyringStore struct {
+	ring keyring.Keyring
+}
+
+// openKeyringStore opens the OS keyring, failing if no supported keyring is available
+func openKeyringStore() (*keyringStore, error) {
+	ring, err := keyring.Open(keyring.Config{
+		ServiceName: secretServiceName,
+		AllowedBackends: []keyring.BackendType{
+			keyring.KeychainBackend,
+			keyring.SecretServiceBackend,
+			keyring.KWalletBackend,
+			keyring.WinCredBackend,
+		},
+		KeychainTrustApplication: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &keyringStore{ring: ring}, nil
+}
+
+func (s *keyringStore) get(name string) (contextSecrets, error) {
+	var secrets contextSecrets
+	item, err := s.ring.Get(name)
+	if errors.Is(err, keyring.ErrKeyNotFound) {
+		return secrets, nil
+	}
+	if err != nil {
+		return secrets, err
+	}
+	if err := json.Unmarshal(item.Data, &secrets); err != nil {
+		return secrets, fmt.Errorf("invalid secrets for context '%s': %v", name, err)
+	}
+	return secrets, nil
+}
+
+func (s *keyringStore) set(name string, secrets contextSecrets) error {
+	data, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	return s.ring.Set(keyring.Item{
+		Key:   name,
+		Data:  data,
+		Label: fmt.Sprintf("ascli context %s", name),
+	})
+}
+
+func (s *keyringStore) remove(name string) error {
+	err := s.ring.Remove(name)
+	if errors.Is(err, keyring.ErrKeyNotFound) || os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// fileStore stores secrets in a JSON file with mode 0600
+type fileStore struct {
+	path string
+}
+
+func (s *fileStore) load() (map[string]contextSecrets, error) {
+	all := make(map[string]contextSecrets)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %v", err)
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file %s: %v", s.path, err)
+	}
+	return all, nil
+}
+
+func (s *fileStore) save(all map[string]contextSecrets) error {
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(s.path, data)
+}
+
+func (s *fileStore) get(name string) (contextSecrets, error) {
+	all, err := s.load()
+	if err != nil {
+		return contextSecrets{}, err
+	}
+	return all[name], nil
+}
+
+func (s *fileStore) set(name string, secrets contextSecrets) error {
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+	all[name] = secrets
+	return s.save(all)
+}
+
+func (s *fileStore) remove(name string) error {
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := all[name]; !ok {
+		return nil
+	}
+	delete(all, name)
+	return s.save(all)
+}
+
+// writePrivateFile writes data to path with mode 0600, tightening the mode of an
+// existing file as well
+func writePrivateFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+// secretsFilePath returns the path of the secrets file next to the contexts file
+func secretsFilePath() string {
+	return filepath.Join(filepath.Dir(contextConfigPath), "secrets.json")
+}
+
+// openSecretStore opens the named secret store
+func openSecretStore(kind string) (secretStore, error) {
+	switch kind {
+	case secretStoreKeyring:
+		return openKeyringStore()
+	case secretStoreFile:
+		return &fileStore{path: secretsFilePath()}, nil
+	}
+	return nil, fmt.Errorf("unknown secret store %q, expected keyring or file", kind)
+}
+
+// storeContextSecrets saves the secrets of a context, preferring the OS keyring
+// and falling back to the secrets file. It returns the store that was used
+func storeContextSecrets(name string, secrets contextSecrets, preferred string) (string, error) {
+	if preferred == "" || preferred == secretStoreKeyring {
+		store, err := openKeyringStore()
+		if err == nil {
+			err = store.set(name, secrets)
+		}
+		if err == nil {
+			return secretStoreKeyring, nil
+		}
+		if preferred == secretStoreKeyring {
+			return "", fmt.Errorf("failed to store secrets in the OS keyring: %v", err)
+		}
+	}
+
+	store, err := openSecretStore(secretStoreFile)
+	if err != nil {
+		return "", err
+	}
+	if err := store.set(name, secrets); err != nil {
+		return "", fmt.Errorf("failed to store secrets: %v", err)
+	}
+	return secretStoreFile, nil
+}
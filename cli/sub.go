@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	subConn     configFlags
+	subKubeOpts kubeOptions
+
+	subAgent    string
+	subTo       string
+	subTimezone string
+	subCount    int64
+)
+
+var subCmd = &cobra.Command{
+	Use:   "sub",
+	Short: "Inspect and move subscriptions through the Pulsar admin API",
+	Long: `List subscriptions with their backlog and move their cursors through the Pulsar
+admin REST API, using the admin URL and credentials of the selected context.
+
+Instead of a topic and subscription, --agent takes them from an Agent resource: its
+request topic and spec.subscriptionName. The cluster is selected like for
+'ascli agent'.
+
+Examples:
+  ascli sub list agent-requests
+  ascli sub list --agent my-agent
+  ascli sub reset agent-requests my-agent --to -15m
+  ascli sub reset --agent my-agent --to latest
+  ascli sub skip agent-requests my-agent --count 1
+  ascli sub clear-backlog --agent my-agent`,
+}
+
+var listSubCmd = &cobra.Command{
+	Use:   "list [topic]",
+	Short: "List the subscriptions of a topic with their backlog",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runListSub,
+}
+
+var resetSubCmd = &cobra.Command{
+	Use:   "reset [topic] [subscription]",
+	Short: "Move a subscription cursor to a message ID or a point in time",
+	Long: `Move a subscription cursor. --to accepts the positions of 'ascli read --from':
+earliest, latest, a message ID, a time, or a relative duration like -15m. Messages
+after the position are delivered again, messages before it are skipped.`,
+	Args: cobra.MaximumNArgs(2),
+	RunE: runResetSub,
+}
+
+var skipSubCmd = &cobra.Command{
+	Use:   "skip [topic] [subscription]",
+	Short: "Skip messages at the head of a subscription backlog",
+	Args:  cobra.MaximumNArgs(2),
+	RunE:  runSkipSub,
+}
+
+var clearBacklogSubCmd = &cobra.Command{
+	Use:   "clear-backlog [topic] [subscription]",
+	Short: "Skip all messages in a subscription backlog",
+	Args:  cobra.MaximumNArgs(2),
+	RunE:  runClearBacklogSub,
+}
+
+func init() {
+	rootCmd.AddCommand(subCmd)
+	for _, cmd := range []*cobra.Command{listSubCmd, resetSubCmd, skipSubCmd, clearBacklogSubCmd} {
+		subCmd.AddCommand(cmd)
+		addAdminFlags(cmd, &subConn)
+		cmd.ValidArgsFunction = completeTopicArg(&subConn)
+	}
+
+	addKubeFlags(subCmd, &subKubeOpts)
+	subCmd.PersistentFlags().StringVar(&subAgent, "agent", "", "Take the topic and subscription from this agent")
+	subCmd.RegisterFlagCompletionFunc("agent", completeAgentNames(&subKubeOpts, -1))
+
+	resetSubCmd.Flags().StringVar(&subTo, "to", "", "Position to move the cursor to: earliest, latest, a message ID, a time or a relative duration like -15m (required)")
+	resetSubCmd.Flags().StringVar(&subTimezone, "timezone", "Local", "Time zone used to parse times without an offset (e.g. UTC, Europe/Berlin)")
+	resetSubCmd.MarkFlagRequired("to")
+	skipSubCmd.Flags().Int64Var(&subCount, "count", 0, "Number of messages to skip (required)")
+	skipSubCmd.MarkFlagRequired("count")
+}
+
+// subTarget resolves the topic and, if needed, the subscription from the arguments
+// or from the agent given by --agent
+func subTarget(args []string, needSubscription bool) (*utils.TopicName, string, error) {
+	var topicName, subscription string
+	if subAgent != "" {
+		if len(args) > 0 {
+			return nil, "", fmt.Errorf("--agent cannot be used with a topic or subscription argument")
+		}
+		client, err := newKubeClient(subKubeOpts)
+		if err != nil {
+			return nil, "", err
+		}
+		a, err := client.getAgent(context.Background(), client.namespace, subAgent)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get agent %s: %v", subAgent, err)
+		}
+		topicName = sourceTopic(a.Spec.RequestSource)
+		if topicName == "" {
+			return nil, "", fmt.Errorf("agent %s does not have a request source", subAgent)
+		}
+		subscription = a.Spec.SubscriptionName
+		if needSubscription && subscription == "" {
+			return nil, "", fmt.Errorf("agent %s does not set spec.subscriptionName, pass the topic and subscription instead", subAgent)
+		}
+	} else {
+		want := 1
+		if needSubscription {
+			want = 2
+		}
+		if len(args) != want {
+			if needSubscription {
+				return nil, "", fmt.Errorf("expected a topic and a subscription, or --agent")
+			}
+			return nil, "", fmt.Errorf("expected a topic, or --agent")
+		}
+		topicName = args[0]
+		if needSubscription {
+			subscription = args[1]
+		}
+	}
+
+	topic, err := parseTopicName(topicName)
+	if err != nil {
+		return nil, "", err
+	}
+	return topic, subscription, nil
+}
+
+// toAdminMessageID converts a parsed message ID to the admin API form
+func toAdminMessageID(value string) (utils.MessageID, error) {
+	switch value {
+	case "earliest":
+		return utils.Earliest, nil
+	case "latest":
+		return utils.Latest, nil
+	}
+	id, err := parseMessageID(value)
+	if err != nil {
+		return utils.MessageID{}, err
+	}
+	return utils.MessageID{
+		LedgerID:       id.LedgerID(),
+		EntryID:        id.EntryID(),
+		PartitionIndex: int(id.PartitionIdx()),
+		BatchIndex:     int(id.BatchIdx()),
+	}, nil
+}
+
+func runListSub(cmd *cobra.Command, args []string) error {
+	topic, _, err := subTarget(args, false)
+	if err != nil {
+		return err
+	}
+	admin, err := newAdminClient(subConn)
+	if err != nil {
+		return err
+	}
+	subs, err := topicSubscriptions(admin, topic)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Topic: %s\n\n", topic)
+	return printSubscriptions(subs)
+}
+
+func runResetSub(cmd *cobra.Command, args []string) error {
+	topic, subscription, err := subTarget(args, true)
+	if err != nil {
+		return err
+	}
+	loc, err := loadTimezone(subTimezone)
+	if err != nil {
+		return err
+	}
+	position, err := parseStartPosition(subTo, loc, time.Now())
+	if err != nil {
+		return err
+	}
+	admin, err := newAdminClient(subConn)
+	if err != nil {
+		return err
+	}
+
+	if position.isTime() {
+		if err := admin.Subscriptions().ResetCursorToTimestamp(*topic, subscription, position.time.UnixMilli()); err != nil {
+			return fmt.Errorf("failed to reset subscription %s: %v", subscription, err)
+		}
+		fmt.Printf("Subscription %s on %s reset to %s\n", subscription, topic, position.time.In(loc).Format("2006-01-02 15:04:05 MST"))
+		return nil
+	}
+
+	id, err := toAdminMessageID(subTo)
+	if err != nil {
+		return err
+	}
+	// A message ID belongs to one partition, whose cursor is the one moved
+	target := topic
+	if id.PartitionIndex >= 0 && topic.GetPartitionIndex() < 0 {
+		target, err = topic.GetPartition(id.PartitionIndex)
+		if err != nil {
+			return err
+		}
+	}
+	if err := admin.Subscriptions().ResetCursorToMessageID(*target, subscription, id); err != nil {
+		return fmt.Errorf("failed to reset subscription %s: %v", subscription, err)
+	}
+	fmt.Printf("Subscription %s on %s reset to %s\n", subscription, target, subTo)
+	return nil
+}
+
+func runSkipSub(cmd *cobra.Command, args []string) error {
+	topic, subscription, err := subTarget(args, true)
+	if err != nil {
+		return err
+	}
+	if subCount <= 0 {
+		return fmt.Errorf("--count must be positive")
+	}
+	admin, err := newAdminClient(subConn)
+	if err != nil {
+		return err
+	}
+	if err := admin.Subscriptions().SkipMessages(*topic, subscription, subCount); err != nil {
+		return fmt.Errorf("failed to skip messages on subscription %s: %v", subscription, err)
+	}
+	fmt.Printf("Skipped %d messages on subscription %s of %s\n", subCount, subscription, topic)
+	return nil
+}
+
+func runClearBacklogSub(cmd *cobra.Command, args []string) error {
+	topic, subscription, err := subTarget(args, true)
+	if err != nil {
+		return err
+	}
+	admin, err := newAdminClient(subConn)
+	if err != nil {
+		return err
+	}
+	if err := admin.Subscriptions().ClearBacklog(*topic, subscription); err != nil {
+		return fmt.Errorf("failed to clear the backlog of subscription %s: %v", subscription, err)
+	}
+	fmt.Printf("Cleared the backlog of subscription %s on %s\n", subscription, topic)
+	return nil
+}
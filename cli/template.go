@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const randomStringAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// templateData is the data passed to payload templates
+type templateData struct {
+	// Seq is the 1-based sequence number of the message within this run
+	Seq int
+	// Index is the 0-based index of the payload within the input
+	Index int
+}
+
+// payloadTemplateFuncs returns the placeholder functions available to payload templates
+func payloadTemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"uuid": func() string {
+			return uuid.New().String()
+		},
+		"randInt": func(min, max int) (int, error) {
+			if max < min {
+				return 0, fmt.Errorf("randInt: max %d is less than min %d", max, min)
+			}
+			return min + rand.Intn(max-min+1), nil
+		},
+		"randFloat": func() float64 {
+			return rand.Float64()
+		},
+		"randString": func(n int) string {
+			b := make([]byte, n)
+			for i := range b {
+				b[i] = randomStringAlphabet[rand.Intn(len(randomStringAlphabet))]
+			}
+			return string(b)
+		},
+		"randChoice": func(choices ...string) (string, error) {
+			if len(choices) == 0 {
+				return "", fmt.Errorf("randChoice: no choices given")
+			}
+			return choices[rand.Intn(len(choices))], nil
+		},
+		"now": func() string {
+			return time.Now().UTC().Format(time.RFC3339)
+		},
+		"unixMillis": func() int64 {
+			return time.Now().UnixMilli()
+		},
+		"unix": func() int64 {
+			return time.Now().Unix()
+		},
+	}
+}
+
+// parsePayloadTemplate parses a payload template using the placeholder functions
+func parsePayloadTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(payloadTemplateFuncs()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// renderPayloadTemplate executes a payload template with the given data
+func renderPayloadTemplate(tmpl *template.Template, data templateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render payload template: %v", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	fsv1alpha1 "github.com/FunctionStream/function-stream/operator/api/v1alpha1"
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	toolKubeOpts kubeOptions
+
+	toolAllNamespaces bool
+	toolIncludeAgents bool
+
+	toolCallJSON          string
+	toolCallResponseTopic string
+	toolCallSkipValidate  bool
+	toolCallTimeout       time.Duration
+	toolCallPulsarURL     string
+	toolCallAuth          authFlags
+)
+
+var toolCmd = &cobra.Command{
+	Use:   "tool",
+	Short: "Inspect and call FunctionStream tools",
+	Long: `Inspect and call the FunctionStream Functions that agents use as tools.
+
+A tool is a Function referenced from an agent's spec.tools. Its input and output
+schemas come from the sourceSchema and sinkSchema of the Package module it runs.
+The cluster is selected like for 'ascli agent'.
+
+Examples:
+  ascli tool list
+  ascli tool describe weather
+  ascli tool call weather --json '{"city": "Paris"}'`,
+}
+
+var listToolCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tool functions",
+	Args:  cobra.NoArgs,
+	RunE:  runListTool,
+}
+
+var describeToolCmd = &cobra.Command{
+	Use:   "describe [function]",
+	Short: "Show a tool with the description and schemas agents receive",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDescribeTool,
+}
+
+var callToolCmd = &cobra.Command{
+	Use:   "call [function]",
+	Short: "Call a tool directly",
+	Long: `Call a tool function directly and print its response.
+
+The input is validated against the module's sourceSchema before it is sent, so
+schema mismatches are reported without a round trip. The response is checked
+against the sinkSchema and a warning is printed if it does not match.
+
+Examples:
+  ascli tool call weather --json '{"city": "Paris"}'
+  echo '{"city": "Paris"}' | ascli tool call weather --json -
+  ascli tool call tools/geocode --json '{"address": "..."}' --skip-validation`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCallTool,
+}
+
+func init() {
+	rootCmd.AddCommand(toolCmd)
+	toolCmd.AddCommand(listToolCmd)
+	toolCmd.AddCommand(describeToolCmd)
+	toolCmd.AddCommand(callToolCmd)
+
+	addKubeFlags(toolCmd, &toolKubeOpts)
+
+	listToolCmd.Flags().BoolVarP(&toolAllNamespaces, "all-namespaces", "A", false, "List tools in all namespaces")
+	listToolCmd.Flags().BoolVar(&toolIncludeAgents, "include-agents", false, "Also list the functions created for agents")
+
+	callToolCmd.Flags().StringVar(&toolCallJSON, "json", "", "JSON input to send, or '-' to read from stdin (required)")
+	callToolCmd.Flags().StringVar(&toolCallResponseTopic, "response-topic", "", "Response topic (auto-generated if not specified)")
+	callToolCmd.Flags().BoolVar(&toolCallSkipValidate, "skip-validation", false, "Send the input without validating it against the sourceSchema")
+	callToolCmd.Flags().DurationVar(&toolCallTimeout, "timeout", 30*time.Second, "Maximum time to wait for the response")
+	callToolCmd.Flags().StringVar(&toolCallPulsarURL, "pulsar-url", "", "Service URL (overrides context)")
+	addAuthFlags(callToolCmd, &toolCallAuth)
+	callToolCmd.MarkFlagRequired("json")
+}
+
+// getTool fetches the Function referenced by a name or namespace/name argument
+func getTool(ctx context.Context, client *kubeClient, value string) (*fsv1alpha1.Function, error) {
+	ref, err := parseToolRef(value)
+	if err != nil {
+		return nil, err
+	}
+	f, err := client.getFunction(ctx, ref.GetNamespacedName(client.namespace).Namespace, ref.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get function %s: %v", ref.String(), err)
+	}
+	return f, nil
+}
+
+// toolDescription returns the description the operator gives the agent for a tool
+func toolDescription(f *fsv1alpha1.Function, module *fsv1alpha1.Module) string {
+	return fmt.Sprintf("%s\n%s", f.Spec.Description, module.Description)
+}
+
+// printSchema prints a schema indented, pretty-printing it if it is JSON
+func printSchema(title, schema string) {
+	fmt.Printf("\n%s:\n", title)
+	if schema == "" {
+		fmt.Println("  <none>")
+		return
+	}
+	var data interface{}
+	text := schema
+	if err := json.Unmarshal([]byte(schema), &data); err == nil {
+		pretty, _ := json.MarshalIndent(data, "", "  ")
+		text = string(pretty)
+	}
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Printf("  %s\n", line)
+	}
+}
+
+func runListTool(cmd *cobra.Command, args []string) error {
+	client, err := newKubeClient(toolKubeOpts)
+	if err != nil {
+		return err
+	}
+
+	namespace := client.namespace
+	if toolAllNamespaces {
+		namespace = ""
+	}
+	functions, err := client.listFunctions(context.Background(), namespace)
+	if err != nil {
+		return fmt.Errorf("failed to list functions: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if toolAllNamespaces {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, "NAME\tPACKAGE\tMODULE\tREQUEST TOPIC\tREADY\tAGE")
+	count := 0
+	for _, f := range functions {
+		// Functions created by the operator for agents carry the agent label
+		if _, ok := f.Labels["agent"]; ok && !toolIncludeAgents {
+			continue
+		}
+		if toolAllNamespaces {
+			fmt.Fprintf(w, "%s\t", f.Namespace)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d/%d\t%s\n",
+			f.Name,
+			f.Spec.PackageRef.Name,
+			f.Spec.Module,
+			valueOrNone(sourceTopic(f.Spec.RequestSource)),
+			f.Status.ReadyReplicas, f.Status.Replicas,
+			formatAge(f.CreationTimestamp.Time))
+		count++
+	}
+	if count == 0 {
+		fmt.Println("No tools found")
+		return nil
+	}
+	return w.Flush()
+}
+
+func runDescribeTool(cmd *cobra.Command, args []string) error {
+	client, err := newKubeClient(toolKubeOpts)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	f, err := getTool(ctx, client, args[0])
+	if err != nil {
+		return err
+	}
+	module, err := client.getFunctionModule(ctx, f)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Name: %s\n", f.Name)
+	fmt.Printf("Namespace: %s\n", f.Namespace)
+	if f.Spec.DisplayName != "" {
+		fmt.Printf("Display Name: %s\n", f.Spec.DisplayName)
+	}
+	pkgNamespace := f.Spec.PackageRef.Namespace
+	if pkgNamespace == "" {
+		pkgNamespace = f.Namespace
+	}
+	fmt.Printf("Package: %s/%s\n", pkgNamespace, f.Spec.PackageRef.Name)
+	fmt.Printf("Module: %s\n", f.Spec.Module)
+	fmt.Printf("Request Topic: %s\n", valueOrNone(sourceTopic(f.Spec.RequestSource)))
+	fmt.Printf("Ready: %d/%d\n", f.Status.ReadyReplicas, f.Status.Replicas)
+	if f.Spec.RequestSource == nil || f.Spec.RequestSource.Pulsar == nil {
+		fmt.Println("Warning: the function has no request source and cannot be used as a tool")
+	}
+
+	fmt.Println("\nDescription:")
+	for _, line := range strings.Split(toolDescription(f, module), "\n") {
+		fmt.Printf("  %s\n", line)
+	}
+	printSchema("Source Schema", module.SourceSchema)
+	printSchema("Sink Schema", module.SinkSchema)
+
+	// Show which agents use the tool. This is skipped if agents cannot be listed
+	// across namespaces
+	agents, err := client.listAgents(ctx, "")
+	if err != nil {
+		return nil
+	}
+	var users []string
+	for _, a := range agents {
+		for _, tool := range a.Spec.Tools {
+			if tool.Name == f.Name && tool.GetNamespacedName(a.Namespace).Namespace == f.Namespace {
+				users = append(users, a.Namespace+"/"+a.Name)
+				break
+			}
+		}
+	}
+	fmt.Println("\nUsed By:")
+	if len(users) == 0 {
+		fmt.Println("  <none>")
+	}
+	for _, user := range users {
+		fmt.Printf("  %s\n", user)
+	}
+	return nil
+}
+
+func runCallTool(cmd *cobra.Command, args []string) error {
+	messageStr, data, err := readJSONInput(toolCallJSON)
+	if err != nil {
+		return err
+	}
+
+	kube, err := newKubeClient(toolKubeOpts)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	f, err := getTool(ctx, kube, args[0])
+	if err != nil {
+		return err
+	}
+	requestTopic := sourceTopic(f.Spec.RequestSource)
+	if requestTopic == "" {
+		return fmt.Errorf("function %s does not have a request source", f.Name)
+	}
+	module, err := kube.getFunctionModule(ctx, f)
+	if err != nil {
+		return err
+	}
+
+	if !toolCallSkipValidate && module.SourceSchema != "" {
+		schema, err := parseJSONSchema(module.SourceSchema)
+		if err != nil {
+			return fmt.Errorf("failed to parse sourceSchema of %s: %v", f.Name, err)
+		}
+		if errs := schema.validate(data, "$"); len(errs) > 0 {
+			return fmt.Errorf("input does not match the sourceSchema of %s:\n  %s", f.Name, strings.Join(errs, "\n  "))
+		}
+	}
+
+	responseTopic := toolCallResponseTopic
+	if responseTopic == "" {
+		responseTopic = fmt.Sprintf("non-persistent://public/default/response-%s", uuid.New().String())
+	}
+
+	// Get configuration from context or command line
+	cfg, err := resolveConfig(configFlags{pulsarURL: toolCallPulsarURL, auth: toolCallAuth})
+	if err != nil {
+		return fmt.Errorf("failed to get context configuration: %v", err)
+	}
+
+	// Create client
+	clientOpts, err := buildClientOptions(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build client options: %v", err)
+	}
+
+	client, err := pulsar.NewClient(clientOpts)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	msg, err := callRPC(client, rpcRequest{
+		topic:         requestTopic,
+		responseTopic: responseTopic,
+		payload:       []byte(messageStr),
+	}, toolCallTimeout)
+	if err != nil {
+		return err
+	}
+
+	printRPCResponse(msg)
+
+	if module.SinkSchema != "" {
+		schema, err := parseJSONSchema(module.SinkSchema)
+		if err != nil {
+			return nil
+		}
+		var response interface{}
+		if err := json.Unmarshal(msg.Payload(), &response); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: response is not JSON and cannot be checked against the sinkSchema\n")
+			return nil
+		}
+		if errs := schema.validate(response, "$"); len(errs) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: response does not match the sinkSchema:\n  %s\n", strings.Join(errs, "\n  "))
+		}
+	}
+	return nil
+}
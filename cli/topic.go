@@ -0,0 +1,431 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsaradmin"
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	topicConn configFlags
+
+	topicOutput       string
+	topicPartitions   int
+	topicForce        bool
+	topicPerPartition bool
+	topicPeekSub      string
+	topicPeekNum      int
+)
+
+var topicCmd = &cobra.Command{
+	Use:   "topic",
+	Short: "Manage topics through the Pulsar admin API",
+	Long: `List, inspect, create and delete topics through the Pulsar admin REST API,
+using the admin URL and credentials of the selected context.
+
+Topics can be given by their short name (public/default is assumed) or in full.
+
+Examples:
+  ascli topic list
+  ascli topic list my-tenant/my-namespace
+  ascli topic stats agent-requests
+  ascli topic create orders --partitions 4
+  ascli topic peek agent-requests --subscription my-agent --num 5
+  ascli topic delete orders`,
+}
+
+var listTopicCmd = &cobra.Command{
+	Use:   "list [tenant/namespace]",
+	Short: "List the topics of a namespace (defaults to public/default)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runListTopic,
+}
+
+var statsTopicCmd = &cobra.Command{
+	Use:   "stats [topic]",
+	Short: "Show the rates, storage and subscriptions of a topic",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runStatsTopic,
+}
+
+var createTopicCmd = &cobra.Command{
+	Use:   "create [topic]",
+	Short: "Create a topic",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runCreateTopic,
+}
+
+var deleteTopicCmd = &cobra.Command{
+	Use:   "delete [topic]",
+	Short: "Delete a topic",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDeleteTopic,
+}
+
+var peekTopicCmd = &cobra.Command{
+	Use:   "peek [topic]",
+	Short: "Show the next messages of a subscription without consuming them",
+	Long: `Show the next messages in the backlog of a subscription without acknowledging
+them. Partitioned topics are peeked one partition at a time, e.g. my-topic-partition-0.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPeekTopic,
+}
+
+func init() {
+	rootCmd.AddCommand(topicCmd)
+	for _, cmd := range []*cobra.Command{listTopicCmd, statsTopicCmd, createTopicCmd, deleteTopicCmd, peekTopicCmd} {
+		topicCmd.AddCommand(cmd)
+		addAdminFlags(cmd, &topicConn)
+	}
+
+	for _, cmd := range []*cobra.Command{statsTopicCmd, deleteTopicCmd, peekTopicCmd} {
+		cmd.ValidArgsFunction = completeTopicArg(&topicConn)
+	}
+
+	listTopicCmd.Flags().StringVarP(&topicOutput, "output", "o", "table", "Output format: table, json or yaml")
+	statsTopicCmd.Flags().StringVarP(&topicOutput, "output", "o", "table", "Output format: table, json or yaml")
+	statsTopicCmd.Flags().BoolVar(&topicPerPartition, "per-partition", false, "Show the stats of each partition of a partitioned topic")
+	createTopicCmd.Flags().IntVar(&topicPartitions, "partitions", 0, "Number of partitions (0 creates a non-partitioned topic)")
+	deleteTopicCmd.Flags().BoolVar(&topicForce, "force", false, "Delete the topic even if it has producers or subscriptions")
+	peekTopicCmd.Flags().StringVarP(&topicPeekSub, "subscription", "s", "", "Subscription whose backlog to peek (required)")
+	peekTopicCmd.Flags().IntVar(&topicPeekNum, "num", 1, "Number of messages to peek")
+	peekTopicCmd.MarkFlagRequired("subscription")
+}
+
+// addAdminFlags registers the flags that select the admin API and its credentials
+func addAdminFlags(cmd *cobra.Command, flags *configFlags) {
+	cmd.Flags().StringVar(&flags.pulsarURL, "pulsar-url", "", "Service URL, used to derive the admin URL (overrides context)")
+	cmd.Flags().StringVar(&flags.adminURL, "admin-url", "", "Admin service URL (overrides context)")
+	addAuthFlags(cmd, &flags.auth)
+}
+
+// newAdminClient creates an admin client for the resolved configuration
+func newAdminClient(flags configFlags) (pulsaradmin.Client, error) {
+	cfg, err := resolveConfig(flags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get context configuration: %v", err)
+	}
+	return buildAdminClient(cfg)
+}
+
+// parseTopicName parses a short or complete topic name
+func parseTopicName(name string) (*utils.TopicName, error) {
+	topic, err := utils.GetTopicName(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic %q: %v", name, err)
+	}
+	return topic, nil
+}
+
+// topicPartitionCount returns the number of partitions of a topic, 0 if it is not
+// partitioned
+func topicPartitionCount(admin pulsaradmin.Client, topic *utils.TopicName) (int, error) {
+	// Partitions of a partitioned topic are topics of their own
+	if topic.GetPartitionIndex() >= 0 {
+		return 0, nil
+	}
+	meta, err := admin.Topics().GetMetadata(*topic)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get metadata of %s: %v", topic, err)
+	}
+	return meta.Partitions, nil
+}
+
+// topicSubscriptions returns the subscription stats of a topic, aggregated over
+// the partitions of a partitioned topic
+func topicSubscriptions(admin pulsaradmin.Client, topic *utils.TopicName) (map[string]utils.SubscriptionStats, error) {
+	partitions, err := topicPartitionCount(admin, topic)
+	if err != nil {
+		return nil, err
+	}
+	if partitions > 0 {
+		stats, err := admin.Topics().GetPartitionedStats(*topic, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stats of %s: %v", topic, err)
+		}
+		return stats.Subscriptions, nil
+	}
+	stats, err := admin.Topics().GetStats(*topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats of %s: %v", topic, err)
+	}
+	return stats.Subscriptions, nil
+}
+
+// printSubscriptions prints subscription stats as a table sorted by name
+func printSubscriptions(subs map[string]utils.SubscriptionStats) error {
+	if len(subs) == 0 {
+		fmt.Println("No subscriptions found")
+		return nil
+	}
+	names := make([]string, 0, len(subs))
+	for name := range subs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "SUBSCRIPTION\tTYPE\tBACKLOG\tUNACKED\tCONSUMERS\tRATE OUT\tOLDEST BACKLOG")
+	for _, name := range names {
+		s := subs[name]
+		oldest := "<none>"
+		if s.EarliestMsgPublishTimeInBacklog > 0 {
+			oldest = formatAge(time.UnixMilli(s.EarliestMsgPublishTimeInBacklog))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.2f/s\t%s\n",
+			name, s.SubType, s.MsgBacklog, s.UnAckedMessages, len(s.Consumers), s.MsgRateOut, oldest)
+	}
+	return w.Flush()
+}
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func runListTopic(cmd *cobra.Command, args []string) error {
+	namespace := "public/default"
+	if len(args) > 0 {
+		namespace = args[0]
+	}
+	ns, err := utils.GetNamespaceName(namespace)
+	if err != nil {
+		return fmt.Errorf("invalid namespace %q: %v", namespace, err)
+	}
+
+	admin, err := newAdminClient(topicConn)
+	if err != nil {
+		return err
+	}
+	partitioned, nonPartitioned, err := admin.Topics().List(*ns)
+	if err != nil {
+		return fmt.Errorf("failed to list topics of %s: %v", namespace, err)
+	}
+
+	type topicEntry struct {
+		Name        string `json:"name"`
+		Partitioned bool   `json:"partitioned"`
+	}
+	var topics []topicEntry
+	for _, name := range partitioned {
+		topics = append(topics, topicEntry{Name: name, Partitioned: true})
+	}
+	for _, name := range nonPartitioned {
+		// Partitions are listed through their partitioned topic
+		if strings.Contains(name, utils.PARTITIONEDTOPICSUFFIX) {
+			continue
+		}
+		topics = append(topics, topicEntry{Name: name})
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Name < topics[j].Name })
+
+	if topicOutput != "table" {
+		return printObject(topics, topicOutput)
+	}
+	if len(topics) == 0 {
+		fmt.Printf("No topics found in %s\n", namespace)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "TOPIC\tPARTITIONED")
+	for _, t := range topics {
+		fmt.Fprintf(w, "%s\t%t\n", t.Name, t.Partitioned)
+	}
+	return w.Flush()
+}
+
+func runStatsTopic(cmd *cobra.Command, args []string) error {
+	topic, err := parseTopicName(args[0])
+	if err != nil {
+		return err
+	}
+	admin, err := newAdminClient(topicConn)
+	if err != nil {
+		return err
+	}
+	partitions, err := topicPartitionCount(admin, topic)
+	if err != nil {
+		return err
+	}
+
+	var stats utils.TopicStats
+	var partitionStats map[string]utils.TopicStats
+	if partitions > 0 {
+		ps, err := admin.Topics().GetPartitionedStats(*topic, topicPerPartition)
+		if err != nil {
+			return fmt.Errorf("failed to get stats of %s: %v", topic, err)
+		}
+		if topicOutput != "table" {
+			return printObject(ps, topicOutput)
+		}
+		stats = utils.TopicStats{
+			MsgRateIn:        ps.MsgRateIn,
+			MsgRateOut:       ps.MsgRateOut,
+			MsgThroughputIn:  ps.MsgThroughputIn,
+			MsgThroughputOut: ps.MsgThroughputOut,
+			AverageMsgSize:   ps.AverageMsgSize,
+			StorageSize:      ps.StorageSize,
+			Publishers:       ps.Publishers,
+			Subscriptions:    ps.Subscriptions,
+		}
+		for _, p := range ps.Partitions {
+			stats.BacklogSize += p.BacklogSize
+		}
+		partitionStats = ps.Partitions
+	} else {
+		stats, err = admin.Topics().GetStats(*topic)
+		if err != nil {
+			return fmt.Errorf("failed to get stats of %s: %v", topic, err)
+		}
+		if topicOutput != "table" {
+			return printObject(stats, topicOutput)
+		}
+	}
+
+	fmt.Printf("Topic: %s\n", topic)
+	if partitions > 0 {
+		fmt.Printf("Partitions: %d\n", partitions)
+	}
+	fmt.Printf("Rate In: %.2f msg/s, %s/s\n", stats.MsgRateIn, formatBytes(int64(stats.MsgThroughputIn)))
+	fmt.Printf("Rate Out: %.2f msg/s, %s/s\n", stats.MsgRateOut, formatBytes(int64(stats.MsgThroughputOut)))
+	fmt.Printf("Average Message Size: %s\n", formatBytes(int64(stats.AverageMsgSize)))
+	fmt.Printf("Storage Size: %s\n", formatBytes(stats.StorageSize))
+	fmt.Printf("Backlog Size: %s\n", formatBytes(stats.BacklogSize))
+	fmt.Printf("Producers: %d\n", len(stats.Publishers))
+
+	if len(partitionStats) > 0 {
+		names := make([]string, 0, len(partitionStats))
+		for name := range partitionStats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Println("\nPartitions:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "  PARTITION\tRATE IN\tRATE OUT\tSTORAGE\tBACKLOG")
+		for _, name := range names {
+			p := partitionStats[name]
+			fmt.Fprintf(w, "  %s\t%.2f/s\t%.2f/s\t%s\t%s\n",
+				name, p.MsgRateIn, p.MsgRateOut, formatBytes(p.StorageSize), formatBytes(p.BacklogSize))
+		}
+		w.Flush()
+	}
+
+	fmt.Println("\nSubscriptions:")
+	return printSubscriptions(stats.Subscriptions)
+}
+
+func runCreateTopic(cmd *cobra.Command, args []string) error {
+	topic, err := parseTopicName(args[0])
+	if err != nil {
+		return err
+	}
+	if topicPartitions < 0 {
+		return fmt.Errorf("--partitions cannot be negative")
+	}
+	admin, err := newAdminClient(topicConn)
+	if err != nil {
+		return err
+	}
+	if err := admin.Topics().Create(*topic, topicPartitions); err != nil {
+		return fmt.Errorf("failed to create topic %s: %v", topic, err)
+	}
+	if topicPartitions > 0 {
+		fmt.Printf("Topic %s created with %d partitions\n", topic, topicPartitions)
+	} else {
+		fmt.Printf("Topic %s created\n", topic)
+	}
+	return nil
+}
+
+func runDeleteTopic(cmd *cobra.Command, args []string) error {
+	topic, err := parseTopicName(args[0])
+	if err != nil {
+		return err
+	}
+	admin, err := newAdminClient(topicConn)
+	if err != nil {
+		return err
+	}
+	partitions, err := topicPartitionCount(admin, topic)
+	if err != nil {
+		return err
+	}
+	if err := admin.Topics().Delete(*topic, topicForce, partitions == 0); err != nil {
+		return fmt.Errorf("failed to delete topic %s: %v", topic, err)
+	}
+	fmt.Printf("Topic %s deleted\n", topic)
+	return nil
+}
+
+func runPeekTopic(cmd *cobra.Command, args []string) error {
+	topic, err := parseTopicName(args[0])
+	if err != nil {
+		return err
+	}
+	admin, err := newAdminClient(topicConn)
+	if err != nil {
+		return err
+	}
+	partitions, err := topicPartitionCount(admin, topic)
+	if err != nil {
+		return err
+	}
+	if partitions > 0 {
+		return fmt.Errorf("%s is partitioned, peek a partition instead, e.g. %s%s0", topic, topic, utils.PARTITIONEDTOPICSUFFIX)
+	}
+
+	// Peeking past the end of the backlog fails, so stop at the backlog size
+	subs, err := topicSubscriptions(admin, topic)
+	if err != nil {
+		return err
+	}
+	sub, ok := subs[topicPeekSub]
+	if !ok {
+		return fmt.Errorf("subscription %s not found on %s", topicPeekSub, topic)
+	}
+	num := topicPeekNum
+	if int64(num) > sub.MsgBacklog {
+		num = int(sub.MsgBacklog)
+	}
+	if num <= 0 {
+		fmt.Printf("Subscription %s has no backlog\n", topicPeekSub)
+		return nil
+	}
+
+	msgs, err := admin.Subscriptions().PeekMessages(*topic, topicPeekSub, num)
+	if err != nil {
+		return fmt.Errorf("failed to peek messages: %v", err)
+	}
+	for i, msg := range msgs {
+		fmt.Println("---")
+		fmt.Printf("Position: %d\n", i+1)
+		fmt.Printf("Message ID: %d:%d\n", msg.MessageID.LedgerID, msg.MessageID.EntryID)
+		fmt.Printf("Message Header: %v\n", msg.Properties)
+
+		var data interface{}
+		if err := json.Unmarshal(msg.Payload, &data); err == nil {
+			prettyJSON, _ := json.MarshalIndent(data, "", "  ")
+			fmt.Println(string(prettyJSON))
+		} else {
+			fmt.Println(string(msg.Payload))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+// pollInterval is how often idle partitions are checked for new messages
+const pollInterval = 100 * time.Millisecond
+
+// topicReader reads a topic from a start position. Partitioned topics are read
+// through one reader per partition and merged in publish time order
+type topicReader struct {
+	readers []pulsar.Reader
+	// partitions holds the partition index each reader reads, or -1 for a
+	// non-partitioned topic
+	partitions []int32
+	heads      []pulsar.Message
+}
+
+// partitionMessage is a message whose ID carries the index of the partition it was
+// read from. Readers of a single partition report index 0 for every partition
+type partitionMessage struct {
+	pulsar.Message
+	id pulsar.MessageID
+}
+
+// ID returns the message ID with the real partition index
+func (m partitionMessage) ID() pulsar.MessageID {
+	return m.id
+}
+
+// withPartition returns msg with the partition index set in its ID
+func withPartition(msg pulsar.Message, partition int32) pulsar.Message {
+	id := msg.ID()
+	return partitionMessage{
+		Message: msg,
+		id:      pulsar.NewMessageID(id.LedgerID(), id.EntryID(), id.BatchIdx(), partition),
+	}
+}
+
+// newTopicReader creates readers for every partition of the topic positioned at start
+func newTopicReader(client pulsar.Client, topic string, start startPosition) (*topicReader, error) {
+	partitions, err := client.TopicPartitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions of topic '%s': %v", topic, err)
+	}
+
+	indexes := make([]int32, len(partitions))
+	for i := range partitions {
+		indexes[i] = int32(i)
+	}
+	if len(partitions) == 1 && !strings.Contains(partitions[0], "-partition-") {
+		indexes[0] = -1
+	}
+
+	// A message ID identifies a single partition, so only that partition is read.
+	// Earliest and latest apply to every partition
+	if !start.isTime() && !start.isBoundary() && indexes[0] >= 0 {
+		partition := messageIDPartition(start.messageID)
+		if partition < 0 {
+			return nil, fmt.Errorf("topic '%s' is partitioned, the message ID must include the partition index", topic)
+		}
+		if partition >= len(partitions) {
+			return nil, fmt.Errorf("topic '%s' has no partition %d", topic, partition)
+		}
+		partitions = partitions[partition : partition+1]
+		indexes = indexes[partition : partition+1]
+	}
+
+	tr := &topicReader{partitions: indexes}
+	for _, partition := range partitions {
+		opts := pulsar.ReaderOptions{
+			Topic:                   partition,
+			StartMessageID:          pulsar.EarliestMessageID(),
+			StartMessageIDInclusive: true,
+		}
+		if !start.isTime() {
+			opts.StartMessageID = start.messageID
+		}
+		reader, err := client.CreateReader(opts)
+		if err != nil {
+			tr.Close()
+			return nil, fmt.Errorf("failed to create reader for '%s': %v", partition, err)
+		}
+		tr.readers = append(tr.readers, reader)
+
+		if start.isTime() {
+			if err := reader.SeekByTime(start.time); err != nil {
+				tr.Close()
+				return nil, fmt.Errorf("failed to seek '%s' to time: %v", partition, err)
+			}
+		}
+	}
+	tr.heads = make([]pulsar.Message, len(tr.readers))
+	return tr, nil
+}
+
+// messageIDPartition returns the partition index of a message ID, or -1 if unset
+func messageIDPartition(id pulsar.MessageID) int {
+	return int(id.PartitionIdx())
+}
+
+// Next returns the next message in publish time order across all partitions,
+// waiting for new messages until ctx is done
+func (tr *topicReader) Next(ctx context.Context) (pulsar.Message, error) {
+	// A single reader needs no merging and can block on the broker directly
+	if len(tr.readers) == 1 {
+		msg, err := tr.readers[0].Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return withPartition(msg, tr.partitions[0]), nil
+	}
+
+	for {
+		for i, reader := range tr.readers {
+			if tr.heads[i] != nil || !reader.HasNext() {
+				continue
+			}
+			msg, err := reader.Next(ctx)
+			if err != nil {
+				return nil, err
+			}
+			tr.heads[i] = withPartition(msg, tr.partitions[i])
+		}
+
+		oldest := -1
+		for i, msg := range tr.heads {
+			if msg == nil {
+				continue
+			}
+			if oldest < 0 || msg.PublishTime().Before(tr.heads[oldest].PublishTime()) {
+				oldest = i
+			}
+		}
+		if oldest >= 0 {
+			msg := tr.heads[oldest]
+			tr.heads[oldest] = nil
+			return msg, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// readsPartition reports whether the reader reads the partition with the given index,
+// -1 being a non-partitioned topic
+func (tr *topicReader) readsPartition(partition int32) bool {
+	return slices.Contains(tr.partitions, partition)
+}
+
+// HasNext reports whether any partition has a message available without waiting
+func (tr *topicReader) HasNext() bool {
+	for i, reader := range tr.readers {
+		if tr.heads[i] != nil || reader.HasNext() {
+			return true
+		}
+	}
+	return false
+}
+
+// Close closes all partition readers
+func (tr *topicReader) Close() {
+	for _, reader := range tr.readers {
+		reader.Close()
+	}
+}
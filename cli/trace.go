@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// traceparentProperty is the message property carrying the W3C trace context
+// (https://www.w3.org/TR/trace-context/) of a message
+const traceparentProperty = "traceparent"
+
+// traceparentEnv may hold the trace context of a caller, e.g. a CI job or a script
+// that is traced itself. Messages sent by ascli then join that trace
+const traceparentEnv = "TRACEPARENT"
+
+// traceContext is a W3C trace context
+type traceContext struct {
+	traceID  string
+	parentID string
+	flags    string
+}
+
+func (t traceContext) String() string {
+	return fmt.Sprintf("00-%s-%s-%s", t.traceID, t.parentID, t.flags)
+}
+
+// parseTraceparent parses a traceparent header value
+func parseTraceparent(value string) (traceContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return traceContext{}, fmt.Errorf("invalid traceparent %q, expected version-traceid-parentid-flags", value)
+	}
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isLowerHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return traceContext{}, fmt.Errorf("invalid traceparent %q: unsupported version", value)
+	}
+	if !isLowerHex(traceID, 32) || traceID == strings.Repeat("0", 32) {
+		return traceContext{}, fmt.Errorf("invalid traceparent %q: invalid trace ID", value)
+	}
+	if !isLowerHex(parentID, 16) || parentID == strings.Repeat("0", 16) {
+		return traceContext{}, fmt.Errorf("invalid traceparent %q: invalid parent ID", value)
+	}
+	if !isLowerHex(flags, 2) {
+		return traceContext{}, fmt.Errorf("invalid traceparent %q: invalid flags", value)
+	}
+	return traceContext{traceID: traceID, parentID: parentID, flags: flags}, nil
+}
+
+// isLowerHex reports whether s is n lowercase hex digits
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// randomHex returns n random bytes in hex
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate trace context: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newTraceContext returns the trace context of a new message. It continues the trace
+// in TRACEPARENT if set, and starts a new sampled trace otherwise
+func newTraceContext() (traceContext, error) {
+	parentID, err := randomHex(8)
+	if err != nil {
+		return traceContext{}, err
+	}
+	if value := os.Getenv(traceparentEnv); value != "" {
+		parent, err := parseTraceparent(value)
+		if err != nil {
+			return traceContext{}, fmt.Errorf("invalid %s: %v", traceparentEnv, err)
+		}
+		return traceContext{traceID: parent.traceID, parentID: parentID, flags: parent.flags}, nil
+	}
+	traceID, err := randomHex(16)
+	if err != nil {
+		return traceContext{}, err
+	}
+	return traceContext{traceID: traceID, parentID: parentID, flags: "01"}, nil
+}
+
+// setTraceparent adds a new trace context to the properties of a message unless the
+// user set a traceparent property already, and returns the trace ID of the message
+func setTraceparent(properties map[string]string) (string, error) {
+	if value, ok := properties[traceparentProperty]; ok {
+		tc, err := parseTraceparent(value)
+		if err != nil {
+			return "", err
+		}
+		return tc.traceID, nil
+	}
+	tc, err := newTraceContext()
+	if err != nil {
+		return "", err
+	}
+	properties[traceparentProperty] = tc.String()
+	return tc.traceID, nil
+}
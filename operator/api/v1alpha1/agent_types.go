@@ -55,13 +55,74 @@ type PostProcessCallback struct {
 }
 
 type ModelConfig struct {
-	// +kubebuilder:validation:Required
-	Model string `json:"model"`
+	// Name of the model. Defaults to the defaultModel of the operator config
+	// +kubebuilder:validation:Optional
+	Model string `json:"model,omitempty"`
 
 	// +kubebuilder:validation:Optional
 	GoogleApiKey string `json:"googleApiKey,omitempty"`
 }
 
+// ObservabilitySpec configures the request metrics the operator collects for an agent.
+type ObservabilitySpec struct {
+	// Collect request rate, latency, error and token metrics by consuming the request
+	// topic and the response topics named in the requests
+	// +kubebuilder:validation:Optional
+	RequestMetrics bool `json:"requestMetrics,omitempty"`
+	// Time after which a request without a response counts as failed. Defaults to 5m
+	// +kubebuilder:validation:Optional
+	ResponseTimeout *metav1.Duration `json:"responseTimeout,omitempty"`
+}
+
+// TracingSpec configures the export of OpenTelemetry traces from an agent.
+type TracingSpec struct {
+	// OTLP endpoint traces are exported to, e.g. http://otel-collector.observability:4317
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Endpoint string `json:"endpoint"`
+	// OTLP protocol of the endpoint
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=grpc;http/protobuf
+	// +kubebuilder:default=grpc
+	Protocol string `json:"protocol,omitempty"`
+	// Export without TLS
+	// +kubebuilder:validation:Optional
+	Insecure bool `json:"insecure,omitempty"`
+	// Headers sent with every export, e.g. for authentication
+	// +kubebuilder:validation:Optional
+	Headers map[string]string `json:"headers,omitempty"`
+	// Service name of the spans. Defaults to <namespace>.<name> of the agent
+	// +kubebuilder:validation:Optional
+	ServiceName string `json:"serviceName,omitempty"`
+	// Percentage of new traces that are sampled. Requests carrying a traceparent follow
+	// the sampling decision of their caller. Defaults to 100
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	SamplingPercentage *int32 `json:"samplingPercentage,omitempty"`
+}
+
+// LimitsSpec caps the load an agent takes on and the model usage of each request.
+// The agent runtime enforces the limits
+type LimitsSpec struct {
+	// Requests started per second. Requests above the rate wait until they fit in it
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	RequestsPerSecond *int32 `json:"requestsPerSecond,omitempty"`
+	// Requests processed at the same time. Further requests wait for one to finish
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxInFlight *int32 `json:"maxInFlight,omitempty"`
+	// Tool calls a single request may make before it fails
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxToolCallsPerRequest *int32 `json:"maxToolCallsPerRequest,omitempty"`
+	// Model tokens a single request may use before it fails
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxTokensPerRequest *int64 `json:"maxTokensPerRequest,omitempty"`
+}
+
 // AgentSpec defines the desired state of Agent.
 type AgentSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -74,8 +135,8 @@ type AgentSpec struct {
 	Description string `json:"description"`
 	// +kubebuilder:validation:Required
 	Instruction string `json:"instruction"`
-	// +kubebuilder:validation:Required
-	Model ModelConfig `json:"model"`
+	// +kubebuilder:validation:Optional
+	Model ModelConfig `json:"model,omitempty"`
 	// +kubebuilder:validation:Optional
 	SubscriptionName string `json:"subscriptionName,omitempty"`
 	// List of sources
@@ -95,6 +156,49 @@ type AgentSpec struct {
 
 	// +kubebuilder:validation:Optional
 	PostProcess *PostProcessCallback `json:"postProcess,omitempty"`
+
+	// Topic that requests are published to when processing them fails, with the error
+	// in the message properties. Defaults to persistent://public/default/dead-letter-<namespace>-<name>,
+	// or the deadLetterTopicPattern of the operator config
+	// +kubebuilder:validation:Optional
+	DeadLetterTopic string `json:"deadLetterTopic,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Observability *ObservabilitySpec `json:"observability,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Tracing *TracingSpec `json:"tracing,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Limits *LimitsSpec `json:"limits,omitempty"`
+
+	// Suspend the agent: its Function is deleted while the agent and its response topic
+	// are kept, and requests wait on its subscription until it is resumed
+	// +kubebuilder:validation:Optional
+	Suspended bool `json:"suspended,omitempty"`
+}
+
+// AgentConditionSuspended is the condition type that tells whether an agent is
+// suspended, by spec.suspended or by an exhausted budget
+const AgentConditionSuspended = "Suspended"
+
+// AgentConditionToolsResolved is the condition type that tells whether all tools of an
+// agent are resolved. Its reason tells why a tool is not, e.g. ToolAccessDenied
+const AgentConditionToolsResolved = "ToolsResolved"
+
+// ThrottlingStatus describes the last time the agent runtime throttled requests
+// because of spec.limits.
+type ThrottlingStatus struct {
+	// Limit that throttled requests: RateLimited, ConcurrencyLimited, ToolCallLimitExceeded
+	// or TokenLimitExceeded
+	Reason string `json:"reason"`
+	// Human readable description of the throttling
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Requests throttled in the reporting interval ending at lastThrottleTime
+	Count int64 `json:"count"`
+	// Time the runtime last reported throttling
+	LastThrottleTime metav1.Time `json:"lastThrottleTime"`
 }
 
 // AgentStatus defines the observed state of Agent.
@@ -103,6 +207,22 @@ type AgentStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
 	FunctionStatus fsv1alpha1.FunctionStatus `json:"functionStatus,omitempty"`
+
+	// Throttling is set once the runtime throttled requests, and cleared when spec.limits
+	// is removed
+	// +optional
+	Throttling *ThrottlingStatus `json:"throttling,omitempty"`
+
+	// DeadLetterTopic is the topic failed requests are published to, spec.deadLetterTopic
+	// or the default of the operator
+	// +optional
+	DeadLetterTopic string `json:"deadLetterTopic,omitempty"`
+
+	// Conditions of the agent
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,125 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Units a budget is counted in
+const (
+	BudgetUnitTokens   = "Tokens"
+	BudgetUnitCurrency = "Currency"
+)
+
+// SuspendedByBudgetAnnotation is set on agents suspended by an exhausted budget, to
+// the name of the budget
+const SuspendedByBudgetAnnotation = "as.agentstream.github.io/suspended-by-budget"
+
+// BudgetPricing is the price of model tokens, used to count a budget in currency.
+type BudgetPricing struct {
+	// Price of one million input tokens
+	// +kubebuilder:validation:Required
+	InputPerMillionTokens resource.Quantity `json:"inputPerMillionTokens"`
+	// Price of one million output tokens
+	// +kubebuilder:validation:Required
+	OutputPerMillionTokens resource.Quantity `json:"outputPerMillionTokens"`
+	// Currency of the prices, for display only
+	// +kubebuilder:validation:Optional
+	Currency string `json:"currency,omitempty"`
+}
+
+// BudgetSpec defines the desired state of Budget.
+// +kubebuilder:validation:XValidation:rule="self.unit != 'Currency' || has(self.pricing)",message="pricing is required when unit is Currency"
+type BudgetSpec struct {
+	// Name of the agent the budget applies to. The budget applies to all agents of its
+	// namespace if not set
+	// +kubebuilder:validation:Optional
+	Agent string `json:"agent,omitempty"`
+	// Unit the limit is counted in: the model tokens used, or their price in currency
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Tokens;Currency
+	// +kubebuilder:default=Tokens
+	Unit string `json:"unit,omitempty"`
+	// Limit for each calendar month (UTC), in the unit of the budget
+	// +kubebuilder:validation:Required
+	Limit resource.Quantity `json:"limit"`
+	// Token prices, required when unit is Currency
+	// +kubebuilder:validation:Optional
+	Pricing *BudgetPricing `json:"pricing,omitempty"`
+	// Percentages of the limit at which a warning event is recorded. Defaults to 80 and 100
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:items:Minimum=1
+	Thresholds []int32 `json:"thresholds,omitempty"`
+	// Suspend the agents of the budget once the limit is spent, until the next month
+	// or until the limit is raised
+	// +kubebuilder:validation:Optional
+	SuspendOnExhaustion bool `json:"suspendOnExhaustion,omitempty"`
+}
+
+// BudgetStatus defines the observed state of Budget.
+type BudgetStatus struct {
+	// Month the usage is counted for, as YYYY-MM (UTC)
+	// +optional
+	Period string `json:"period,omitempty"`
+	// Input tokens used in the period
+	// +optional
+	InputTokens int64 `json:"inputTokens,omitempty"`
+	// Output tokens used in the period
+	// +optional
+	OutputTokens int64 `json:"outputTokens,omitempty"`
+	// Spent in the period, in the unit of the budget
+	// +optional
+	Spent *resource.Quantity `json:"spent,omitempty"`
+	// Highest threshold reached in the period
+	// +optional
+	LastThreshold int32 `json:"lastThreshold,omitempty"`
+	// Agents suspended because the budget is exhausted
+	// +optional
+	SuspendedAgents []string `json:"suspendedAgents,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Agent",type=string,JSONPath=`.spec.agent`
+// +kubebuilder:printcolumn:name="Unit",type=string,JSONPath=`.spec.unit`
+// +kubebuilder:printcolumn:name="Limit",type=string,JSONPath=`.spec.limit`
+// +kubebuilder:printcolumn:name="Spent",type=string,JSONPath=`.status.spent`
+// +kubebuilder:printcolumn:name="Period",type=string,JSONPath=`.status.period`
+
+// Budget is the Schema for the budgets API.
+type Budget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BudgetSpec   `json:"spec,omitempty"`
+	Status BudgetStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BudgetList contains a list of Budget.
+type BudgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Budget `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Budget{}, &BudgetList{})
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ToolGrantSubject selects the agents a ToolGrant lets use its tools.
+type ToolGrantSubject struct {
+	// Namespace of the agents
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+	// Name of the agent. All agents of the namespace may use the tools if not set
+	// +kubebuilder:validation:Optional
+	Agent string `json:"agent,omitempty"`
+}
+
+// ToolGrantSpec defines which agents of other namespaces may use tools of the
+// namespace of the grant.
+type ToolGrantSpec struct {
+	// Names of the tool Functions the grant applies to. The grant applies to all
+	// Functions of its namespace if not set
+	// +kubebuilder:validation:Optional
+	Tools []string `json:"tools,omitempty"`
+	// Agents that may use the tools
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	From []ToolGrantSubject `json:"from"`
+}
+
+// +kubebuilder:object:root=true
+
+// ToolGrant lets agents of other namespaces use tool Functions of its namespace.
+// Agents may always use tools of their own namespace.
+type ToolGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ToolGrantSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ToolGrantList contains a list of ToolGrant.
+type ToolGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ToolGrant `json:"items"`
+}
+
+// Allows reports whether the grant lets an agent use a tool of its namespace
+func (g *ToolGrant) Allows(agentNamespace, agentName, tool string) bool {
+	if len(g.Spec.Tools) > 0 && !slices.Contains(g.Spec.Tools, tool) {
+		return false
+	}
+	for _, from := range g.Spec.From {
+		if from.Namespace == agentNamespace && (from.Agent == "" || from.Agent == agentName) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	SchemeBuilder.Register(&ToolGrant{}, &ToolGrantList{})
+}
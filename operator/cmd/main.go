@@ -21,16 +21,22 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -40,6 +46,7 @@ import (
 	fsv1alpha1 "github.com/FunctionStream/function-stream/operator/api/v1alpha1"
 	asv1alpha1 "github.com/agentstream/agentstream/operator/api/v1alpha1"
 	"github.com/agentstream/agentstream/operator/internal/controller"
+	"github.com/agentstream/agentstream/operator/internal/observability"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -70,6 +77,9 @@ func main() {
 	var pulsarAuthParams string
 	var agentPackage string
 	var agentModule string
+	var usageTopic string
+	var watchNamespaces string
+	var operatorConfig string
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -94,6 +104,14 @@ func main() {
 	flag.StringVar(&pulsarAuthParams, "pulsar-auth-params", os.Getenv("PULSAR_AUTH_PARAMS"), "Pulsar auth params")
 	flag.StringVar(&agentPackage, "agent-package", os.Getenv("AGENT_PACKAGE"), "Agent package name")
 	flag.StringVar(&agentModule, "agent-module", os.Getenv("AGENT_MODULE"), "Agent module name")
+	flag.StringVar(&usageTopic, "usage-topic", observability.DefaultUsageTopic,
+		"The topic agents report their model usage on for budgets. Set to empty to disable usage reporting.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", os.Getenv("WATCH_NAMESPACES"),
+		"Comma-separated namespaces the operator watches, and agents may reference tools in. "+
+			"All namespaces are watched if not set.")
+	flag.StringVar(&operatorConfig, "operator-config", os.Getenv("OPERATOR_CONFIG"),
+		"The <namespace>/<name> of a ConfigMap overriding the agent package and module, default model, "+
+			"Pulsar connection and topic patterns of agents at runtime.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -106,10 +124,26 @@ func main() {
 		PulsarAuthParams: pulsarAuthParams,
 		AgentPackage:     agentPackage,
 		AgentModule:      agentModule,
+		UsageTopic:       usageTopic,
+	}
+	for _, ns := range strings.Split(watchNamespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			config.WatchNamespaces = append(config.WatchNamespaces, ns)
+		}
 	}
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	var operatorConfigName types.NamespacedName
+	if operatorConfig != "" {
+		ns, name, ok := strings.Cut(operatorConfig, "/")
+		if !ok || ns == "" || name == "" {
+			setupLog.Error(nil, "--operator-config must be <namespace>/<name>", "operatorConfig", operatorConfig)
+			os.Exit(1)
+		}
+		operatorConfigName = types.NamespacedName{Namespace: ns, Name: name}
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -199,8 +233,29 @@ func main() {
 		})
 	}
 
+	// Restrict the cache, and with it the RBAC the operator needs, to the watched namespaces
+	var cacheOptions cache.Options
+	if len(config.WatchNamespaces) > 0 {
+		cacheOptions.DefaultNamespaces = map[string]cache.Config{}
+		for _, ns := range config.WatchNamespaces {
+			cacheOptions.DefaultNamespaces[ns] = cache.Config{}
+		}
+		setupLog.Info("Watching namespaces", "namespaces", config.WatchNamespaces)
+	}
+	// Only the operator config is cached of all ConfigMaps
+	if operatorConfigName.Name != "" {
+		cacheOptions.ByObject = map[client.Object]cache.ByObject{
+			&corev1.ConfigMap{}: {
+				Namespaces: map[string]cache.Config{operatorConfigName.Namespace: {}},
+				Field:      fields.OneTermEqualSelector("metadata.name", operatorConfigName.Name),
+			},
+		}
+		setupLog.Info("Watching operator config", "configMap", operatorConfigName)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
+		Cache:                  cacheOptions,
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
@@ -223,14 +278,54 @@ func main() {
 		os.Exit(1)
 	}
 
+	budgetReconciler := &controller.BudgetReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		Recorder:        mgr.GetEventRecorderFor("budget-controller"),
+		WatchNamespaces: config.WatchNamespaces,
+	}
+
+	// Request metrics and model usage of agents are collected through the Pulsar
+	// service the agents use. The connection is rebuilt when the operator config
+	// changes it, and nothing is consumed while no service URL is set
+	conn, err := observability.NewConnection(observability.ConnectionSettings{
+		ServiceURL: pulsarServiceUrl,
+		AuthPlugin: pulsarAuthPlugin,
+		AuthParams: pulsarAuthParams,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to create Pulsar client")
+		os.Exit(1)
+	}
+	gateway := observability.NewGateway(conn)
+	if err := mgr.Add(gateway); err != nil {
+		setupLog.Error(err, "unable to add request metrics gateway to manager")
+		os.Exit(1)
+	}
+	if config.UsageTopic != "" {
+		collector := observability.NewUsageCollector(conn, config.UsageTopic, budgetReconciler.RecordUsage)
+		if err := mgr.Add(collector); err != nil {
+			setupLog.Error(err, "unable to add usage collector to manager")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&controller.AgentReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Config: config,
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("agent-controller"),
+		Config:         config,
+		OperatorConfig: operatorConfigName,
+		Connection:     conn,
+		Gateway:        gateway,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Agent")
 		os.Exit(1)
 	}
+	if err = budgetReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Budget")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -18,19 +18,28 @@ package controller
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
+	"time"
 
 	fsv1alpha1 "github.com/FunctionStream/function-stream/operator/api/v1alpha1"
 	fsutils "github.com/FunctionStream/function-stream/operator/utils"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -38,6 +47,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	asv1alpha1 "github.com/agentstream/agentstream/operator/api/v1alpha1"
+	"github.com/agentstream/agentstream/operator/internal/observability"
 	"github.com/google/uuid"
 )
 
@@ -47,13 +57,84 @@ type Config struct {
 	PulsarAuthParams string
 	AgentPackage     string
 	AgentModule      string
+	// DefaultModel is the model of agents that do not set spec.model.model
+	DefaultModel string
+	// Topic patterns of the default response, dead-letter and throttle topics. The
+	// Default*TopicPattern constants are used if they are empty
+	ResponseTopicPattern   string
+	DeadLetterTopicPattern string
+	ThrottleTopicPattern   string
+	// UsageTopic is the topic agent runtimes report their model usage on for budgets.
+	// Usage is not reported if it is empty, or if no Pulsar service URL is set, as the
+	// operator could not consume it
+	UsageTopic string
+	// WatchNamespaces are the namespaces the operator is restricted to. Agents may only
+	// reference tools in these namespaces. All namespaces are watched if it is empty
+	WatchNamespaces []string
+}
+
+// namespaceWatched reports whether the operator watches a namespace
+func namespaceWatched(watchNamespaces []string, namespace string) bool {
+	return len(watchNamespaces) == 0 || slices.Contains(watchNamespaces, namespace)
+}
+
+// Reasons of the events recorded on agents
+const (
+	ReasonFunctionCreated          = "FunctionCreated"
+	ReasonFunctionUpdated          = "FunctionUpdated"
+	ReasonToolNotFound             = "ToolNotFound"
+	ReasonPackageNotFound          = "PackageNotFound"
+	ReasonModuleNotFound           = "ModuleNotFound"
+	ReasonToolRequestSourceMissing = "ToolRequestSourceMissing"
+	ReasonToolNamespaceNotAllowed  = "ToolNamespaceNotAllowed"
+	ReasonToolAccessDenied         = "ToolAccessDenied"
+	ReasonResponseTopicAssigned    = "ResponseTopicAssigned"
+	ReasonStatusSyncFailed         = "StatusSyncFailed"
+	ReasonTopicWatchFailed         = "TopicWatchFailed"
+	ReasonThrottled                = "Throttled"
+	ReasonSuspended                = "Suspended"
+	ReasonResumed                  = "Resumed"
+	ReasonInvalidOperatorConfig    = "InvalidOperatorConfig"
+	ReasonOperatorConfigKeyIgnored = "OperatorConfigKeyIgnored"
+)
+
+// Reasons of the Suspended condition of agents
+const (
+	ConditionReasonSuspendRequested = "SuspendRequested"
+	ConditionReasonBudgetExhausted  = "BudgetExhausted"
+	ConditionReasonActive           = "Active"
+)
+
+// ConditionReasonToolsResolved is the reason of the ToolsResolved condition once all
+// tools of an agent are resolved. Otherwise its reason is that of the failure, such as
+// ReasonToolAccessDenied
+const ConditionReasonToolsResolved = "Resolved"
+
+// toolResolutionError is returned when a tool of an agent cannot be resolved
+type toolResolutionError struct {
+	reason  string
+	message string
+}
+
+func (e *toolResolutionError) Error() string {
+	return e.message
 }
 
 // AgentReconciler reconciles a Agent object
 type AgentReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
-	Config Config
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	Config   Config
+	// OperatorConfig is the ConfigMap that overrides Config at runtime. Agents are
+	// reconciled again when it changes. Config is used as is if it is not set
+	OperatorConfig types.NamespacedName
+	// Connection is the Pulsar client Gateway and the usage collector consume through.
+	// It follows the Pulsar settings of the operator config
+	Connection *observability.Connection
+	// Gateway collects the request metrics of agents with spec.observability set and
+	// the throttle reports of agents with spec.limits set
+	Gateway *observability.Gateway
 }
 
 // parsePackageRef parses a package reference string in the format "namespace.name" or "name"
@@ -76,6 +157,9 @@ func parsePackageRef(pkgRef string) (string, string) {
 // +kubebuilder:rbac:groups=fs.functionstream.github.io,resources=functions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=fs.functionstream.github.io,resources=functions/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=fs.functionstream.github.io,resources=packages,verbs=get;list;watch
+// +kubebuilder:rbac:groups=as.agentstream.github.io,resources=toolgrants,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -90,26 +174,67 @@ func (r *AgentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	log := logf.FromContext(ctx)
 	log.Info("Reconciling Agent", "agent", req.NamespacedName)
 
+	start := time.Now()
 	var agent asv1alpha1.Agent
 	if err := r.Get(ctx, req.NamespacedName, &agent); err != nil {
 		if errors.IsNotFound(err) {
+			forgetAgentMetrics(req.NamespacedName)
+			if r.Gateway != nil {
+				r.Gateway.Forget(req.NamespacedName)
+			}
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
 	}
+	defer observeReconcile(req.NamespacedName, start)
+	agentTools.WithLabelValues(agent.Namespace, agent.Name).Set(float64(len(agent.Spec.Tools)))
+
+	if agent.Spec.Suspended {
+		return ctrl.Result{}, r.suspend(ctx, &agent, ConditionReasonSuspendRequested, "spec.suspended is set")
+	}
+	if budget, ok := agent.Annotations[asv1alpha1.SuspendedByBudgetAnnotation]; ok {
+		return ctrl.Result{}, r.suspend(ctx, &agent, ConditionReasonBudgetExhausted, fmt.Sprintf("budget %s is exhausted", budget))
+	}
+	wasSuspended := meta.IsStatusConditionTrue(agent.Status.Conditions, asv1alpha1.AgentConditionSuspended)
 
-	functionCfg, err := r.buildFunctionConfig(ctx, &agent)
+	config, err := r.operatorConfig(ctx)
 	if err != nil {
+		r.Recorder.Eventf(&agent, corev1.EventTypeWarning, ReasonInvalidOperatorConfig, "%v", err)
+		return ctrl.Result{}, err
+	}
+	if err := r.syncConnection(config); err != nil {
+		r.Recorder.Eventf(&agent, corev1.EventTypeWarning, ReasonInvalidOperatorConfig, "%v", err)
+		return ctrl.Result{}, err
+	}
+
+	// Tools that cannot be resolved are left out of the config, so that a tool whose
+	// grant is revoked is taken away from the running agent
+	functionCfg, err := r.buildFunctionConfig(ctx, &agent, config)
+	var toolErr *toolResolutionError
+	if err != nil && !stderrors.As(err, &toolErr) {
+		agentPhases.set(req.NamespacedName, PhaseFailed)
 		return ctrl.Result{}, fmt.Errorf("failed to build function config for agent %s: %v", agent.Name, err)
 	}
+	toolsResolved := metav1.Condition{
+		Type:               asv1alpha1.AgentConditionToolsResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             ConditionReasonToolsResolved,
+		Message:            fmt.Sprintf("%d tools resolved", len(agent.Spec.Tools)),
+		ObservedGeneration: agent.Generation,
+	}
+	if toolErr != nil {
+		toolsResolved.Status = metav1.ConditionFalse
+		toolsResolved.Reason = toolErr.reason
+		toolsResolved.Message = toolErr.message
+	}
 
 	labels := map[string]string{
 		"agent": agent.Name,
 	}
 
 	// Parse package reference
-	pkgNamespace, pkgName := parsePackageRef(r.Config.AgentPackage)
-	moduleName := r.Config.AgentModule
+	pkgNamespace, pkgName := parsePackageRef(config.AgentPackage)
+	moduleName := config.AgentModule
 	if moduleName == "" {
 		moduleName = "agent"
 	}
@@ -151,30 +276,189 @@ func (r *AgentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 			if err != nil {
 				return fsutils.HandleReconcileError(log, err, "Conflict when updating Function, will retry automatically")
 			}
+			r.Recorder.Eventf(&agent, corev1.EventTypeNormal, ReasonFunctionUpdated, "Updated Function %s", existing.Name)
 		}
 	} else if errors.IsNotFound(deployErr) {
 		err = r.Create(ctx, function)
 		if err != nil {
 			return fsutils.HandleReconcileError(log, err, "Conflict when creating Function, will retry automatically")
 		}
+		r.Recorder.Eventf(&agent, corev1.EventTypeNormal, ReasonFunctionCreated, "Created Function %s", function.Name)
+		if wasSuspended {
+			r.Recorder.Eventf(&agent, corev1.EventTypeNormal, ReasonResumed, "Resumed, requests are consumed from the subscription again")
+		}
 	} else {
 		return ctrl.Result{}, deployErr
 	}
+	lastFunctionSync.WithLabelValues(agent.Namespace, agent.Name).SetToCurrentTime()
 
 	if err := r.Get(ctx, types.NamespacedName{Name: function.Name, Namespace: function.Namespace}, &existing); err == nil {
+		if toolErr != nil {
+			agentPhases.set(req.NamespacedName, PhaseFailed)
+		} else {
+			agentPhases.set(req.NamespacedName, functionPhase(&existing.Status))
+		}
+		throttling := r.throttlingStatus(&agent)
+		conditions := agent.Status.Conditions
 		agent.Status = convertFunctionStatusToAgentStatus(&existing.Status)
+		agent.Status.Throttling = throttling
+		agent.Status.Conditions = conditions
+		agent.Status.DeadLetterTopic = deadLetterTopic(&agent, config.DeadLetterTopicPattern)
+		meta.SetStatusCondition(&agent.Status.Conditions, metav1.Condition{
+			Type:               asv1alpha1.AgentConditionSuspended,
+			Status:             metav1.ConditionFalse,
+			Reason:             ConditionReasonActive,
+			Message:            "The agent is not suspended",
+			ObservedGeneration: agent.Generation,
+		})
+		meta.SetStatusCondition(&agent.Status.Conditions, toolsResolved)
 		if err := r.Status().Update(ctx, &agent); err != nil {
+			if !errors.IsConflict(err) {
+				r.Recorder.Eventf(&agent, corev1.EventTypeWarning, ReasonStatusSyncFailed, "Failed to update status from Function %s: %v", function.Name, err)
+			}
 			return fsutils.HandleReconcileError(log, err, "Conflict when updating Function status, will retry automatically")
 		}
 	}
 
+	if err := r.syncGatewayWatch(&agent, config); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if toolErr != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to resolve the tools of agent %s: %v", agent.Name, toolErr)
+	}
 	return ctrl.Result{}, nil
 }
 
-func (r *AgentReconciler) buildFunctionConfig(ctx context.Context, agent *asv1alpha1.Agent) (map[string]v1.JSON, error) {
+// suspend deletes the Function of an agent, so it stops consuming requests while they
+// are kept on its subscription, and sets the Suspended condition in place of the
+// Function status
+func (r *AgentReconciler) suspend(ctx context.Context, agent *asv1alpha1.Agent, reason, cause string) error {
+	agentPhases.set(client.ObjectKeyFromObject(agent), PhaseSuspended)
+
+	var existing fsv1alpha1.Function
+	err := r.Get(ctx, types.NamespacedName{Name: agent.Name, Namespace: agent.Namespace}, &existing)
+	if err == nil && metav1.IsControlledBy(&existing, agent) {
+		if err := r.Delete(ctx, &existing); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete Function %s: %v", existing.Name, err)
+		}
+		r.Recorder.Eventf(agent, corev1.EventTypeWarning, ReasonSuspended, "Deleted Function %s, %s", existing.Name, cause)
+	} else if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	status := asv1alpha1.AgentStatus{
+		DeadLetterTopic: agent.Status.DeadLetterTopic,
+		Conditions:      append([]metav1.Condition(nil), agent.Status.Conditions...),
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               asv1alpha1.AgentConditionSuspended,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            "The agent is suspended because " + cause,
+		ObservedGeneration: agent.Generation,
+	})
+	if !reflect.DeepEqual(agent.Status, status) {
+		agent.Status = status
+		if err := r.Status().Update(ctx, agent); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncConnection moves the operator to the Pulsar connection of the config, so that
+// it consumes the topics of agents through the connection their runtimes use
+func (r *AgentReconciler) syncConnection(config Config) error {
+	if r.Connection == nil {
+		return nil
+	}
+	if err := r.Connection.Update(observability.ConnectionSettings{
+		ServiceURL: config.PulsarServiceURL,
+		AuthPlugin: config.PulsarAuthPlugin,
+		AuthParams: config.PulsarAuthParams,
+	}); err != nil {
+		return fmt.Errorf("invalid Pulsar connection: %v", err)
+	}
+	return nil
+}
+
+// syncGatewayWatch starts or stops consuming the topics of an agent according to
+// spec.observability and spec.limits
+func (r *AgentReconciler) syncGatewayWatch(agent *asv1alpha1.Agent, config Config) error {
+	if r.Gateway == nil {
+		return nil
+	}
+	key := types.NamespacedName{Namespace: agent.Namespace, Name: agent.Name}
+	target := observability.Target{}
+	if obs := agent.Spec.Observability; obs != nil && obs.RequestMetrics &&
+		agent.Spec.RequestSource != nil && agent.Spec.RequestSource.Pulsar != nil {
+		target.RequestTopic = agent.Spec.RequestSource.Pulsar.Topic
+		if rs := agent.Spec.ResponseSource; rs != nil && rs.Pulsar != nil {
+			target.ResponseTopic = rs.Pulsar.Topic
+		}
+		if sink := agent.Spec.Sink; sink != nil && sink.Pulsar != nil {
+			target.SinkTopic = sink.Pulsar.Topic
+		}
+		if obs.ResponseTimeout != nil {
+			target.ResponseTimeout = obs.ResponseTimeout.Duration
+		}
+	}
+	if agent.Spec.Limits != nil {
+		target.ThrottleTopic = throttleTopic(agent, config.ThrottleTopicPattern)
+	}
+	if target.RequestTopic == "" && target.ThrottleTopic == "" {
+		r.Gateway.Forget(key)
+		return nil
+	}
+
+	if err := r.Gateway.Watch(key, target); err != nil {
+		r.Recorder.Eventf(agent, corev1.EventTypeWarning, ReasonTopicWatchFailed, "Failed to consume the topics of the agent: %v", err)
+		return fmt.Errorf("failed to consume the topics of agent %s: %v", agent.Name, err)
+	}
+	return nil
+}
+
+// throttlingStatus returns the throttling status of an agent, updated from the last
+// throttle report of its runtime
+func (r *AgentReconciler) throttlingStatus(agent *asv1alpha1.Agent) *asv1alpha1.ThrottlingStatus {
+	if agent.Spec.Limits == nil {
+		return nil
+	}
+	current := agent.Status.Throttling
+	if r.Gateway == nil {
+		return current
+	}
+	report, ok := r.Gateway.Throttling(types.NamespacedName{Namespace: agent.Namespace, Name: agent.Name})
+	// Status times are stored in seconds, so the report is compared at that precision
+	// to not report it again on every reconcile
+	reported := metav1.NewTime(report.Time.Truncate(time.Second))
+	if !ok || (current != nil && !reported.After(current.LastThrottleTime.Time)) {
+		return current
+	}
+	r.Recorder.Eventf(agent, corev1.EventTypeWarning, ReasonThrottled, "Throttled %d requests (%s): %s", report.Count, report.Reason, report.Message)
+	return &asv1alpha1.ThrottlingStatus{
+		Reason:           report.Reason,
+		Message:          report.Message,
+		Count:            report.Count,
+		LastThrottleTime: reported,
+	}
+}
+
+// buildFunctionConfig builds the Function config of an agent with the operator
+// config. If tools cannot be resolved, the config is returned without them along
+// with a *toolResolutionError
+func (r *AgentReconciler) buildFunctionConfig(ctx context.Context, agent *asv1alpha1.Agent, config Config) (map[string]v1.JSON, error) {
 	cfg := map[string]v1.JSON{}
 
-	modelConfigBytes, err := json.Marshal(agent.Spec.Model)
+	model := agent.Spec.Model
+	if model.Model == "" {
+		model.Model = config.DefaultModel
+	}
+	if model.Model == "" {
+		return nil, fmt.Errorf("spec.model.model is not set and no default model is configured")
+	}
+	modelConfigBytes, err := json.Marshal(model)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal model configuration: %v", err)
 	}
@@ -183,9 +467,9 @@ func (r *AgentReconciler) buildFunctionConfig(ctx context.Context, agent *asv1al
 	}
 
 	pulsarCfg := map[string]interface{}{
-		"serviceUrl": r.Config.PulsarServiceURL,
-		"authPlugin": r.Config.PulsarAuthPlugin,
-		"authParams": r.Config.PulsarAuthParams,
+		"serviceUrl": config.PulsarServiceURL,
+		"authPlugin": config.PulsarAuthPlugin,
+		"authParams": config.PulsarAuthParams,
 	}
 	pulsarCfgBytes, err := json.Marshal(pulsarCfg)
 	if err != nil {
@@ -199,7 +483,11 @@ func (r *AgentReconciler) buildFunctionConfig(ctx context.Context, agent *asv1al
 	responseSource := agent.Spec.ResponseSource
 	if responseSource == nil {
 		// Generate default response source topic
-		defaultTopic := fmt.Sprintf("non-persistent://public/default/response-source-%s-%s", agent.Name, uuid.New().String())
+		pattern := config.ResponseTopicPattern
+		if pattern == "" {
+			pattern = DefaultResponseTopicPattern
+		}
+		defaultTopic := topicName(pattern, agent, uuid.New().String())
 
 		// Create default ResponseSource
 		responseSource = &fsv1alpha1.SourceSpec{
@@ -213,6 +501,7 @@ func (r *AgentReconciler) buildFunctionConfig(ctx context.Context, agent *asv1al
 		if err := r.Update(ctx, agent); err != nil {
 			return nil, fmt.Errorf("failed to update agent with default response source: %v", err)
 		}
+		r.Recorder.Eventf(agent, corev1.EventTypeNormal, ReasonResponseTopicAssigned, "Assigned default response topic %s", defaultTopic)
 	} else if responseSource.Pulsar == nil || responseSource.Pulsar.Topic == "" {
 		// If ResponseSource is set but Pulsar is nil or Topic is empty, throw an error
 		return nil, fmt.Errorf("invalid ResponseSource configuration: ResponseSource is set but Pulsar is nil or Topic is empty")
@@ -226,9 +515,37 @@ func (r *AgentReconciler) buildFunctionConfig(ctx context.Context, agent *asv1al
 		Raw: responseSourceBytes,
 	}
 
-	agentCtx, err := r.buildAgentContext(ctx, agent)
+	deadLetterTopicBytes, err := json.Marshal(deadLetterTopic(agent, config.DeadLetterTopicPattern))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to marshal dead-letter topic: %v", err)
+	}
+	cfg["deadLetterTopic"] = v1.JSON{
+		Raw: deadLetterTopicBytes,
+	}
+
+	if limits := limitsConfig(agent, config.ThrottleTopicPattern); limits != nil {
+		limitsBytes, err := json.Marshal(limits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal limits configuration: %v", err)
+		}
+		cfg["limits"] = v1.JSON{
+			Raw: limitsBytes,
+		}
+	}
+
+	if tracing := tracingConfig(agent); tracing != nil {
+		tracingBytes, err := json.Marshal(tracing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tracing configuration: %v", err)
+		}
+		cfg["tracing"] = v1.JSON{
+			Raw: tracingBytes,
+		}
+	}
+
+	agentCtx, toolErr := r.buildAgentContext(ctx, agent)
+	if agentCtx == nil {
+		return nil, toolErr
 	}
 
 	agentCtxBytes, err := json.Marshal(agentCtx)
@@ -239,8 +556,118 @@ func (r *AgentReconciler) buildFunctionConfig(ctx context.Context, agent *asv1al
 		Raw: agentCtxBytes,
 	}
 
+	if config.UsageTopic != "" && config.PulsarServiceURL != "" {
+		usageBytes, err := json.Marshal(UsageConfig{
+			Topic:     config.UsageTopic,
+			Namespace: agent.Namespace,
+			Agent:     agent.Name,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal usage configuration: %v", err)
+		}
+		cfg["usage"] = v1.JSON{
+			Raw: usageBytes,
+		}
+	}
+
+	if toolErr != nil {
+		return cfg, toolErr
+	}
 	return cfg, nil
+}
+
+// deadLetterTopic returns the topic failed requests of an agent are published to.
+// The default is derived from the agent's namespace and name only, so it stays the
+// same across reconciles. It is reported in status.deadLetterTopic for clients
+func deadLetterTopic(agent *asv1alpha1.Agent, pattern string) string {
+	if agent.Spec.DeadLetterTopic != "" {
+		return agent.Spec.DeadLetterTopic
+	}
+	if pattern == "" {
+		pattern = DefaultDeadLetterTopicPattern
+	}
+	return topicName(pattern, agent, "")
+}
 
+// throttleTopic returns the topic the runtime of an agent reports throttling on
+func throttleTopic(agent *asv1alpha1.Agent, pattern string) string {
+	if pattern == "" {
+		pattern = DefaultThrottleTopicPattern
+	}
+	return topicName(pattern, agent, "")
+}
+
+// LimitsConfig is the configuration of the limits the agent runtime enforces.
+type LimitsConfig struct {
+	RequestsPerSecond      *int32 `json:"requestsPerSecond,omitempty"`
+	MaxInFlight            *int32 `json:"maxInFlight,omitempty"`
+	MaxToolCallsPerRequest *int32 `json:"maxToolCallsPerRequest,omitempty"`
+	MaxTokensPerRequest    *int64 `json:"maxTokensPerRequest,omitempty"`
+	ThrottleTopic          string `json:"throttleTopic"`
+}
+
+// limitsConfig returns the limits configuration of an agent, or nil if it has no limits
+func limitsConfig(agent *asv1alpha1.Agent, throttleTopicPattern string) *LimitsConfig {
+	limits := agent.Spec.Limits
+	if limits == nil {
+		return nil
+	}
+	return &LimitsConfig{
+		RequestsPerSecond:      limits.RequestsPerSecond,
+		MaxInFlight:            limits.MaxInFlight,
+		MaxToolCallsPerRequest: limits.MaxToolCallsPerRequest,
+		MaxTokensPerRequest:    limits.MaxTokensPerRequest,
+		ThrottleTopic:          throttleTopic(agent, throttleTopicPattern),
+	}
+}
+
+// UsageConfig is where the agent runtime reports its model usage for budgets.
+type UsageConfig struct {
+	Topic     string `json:"topic"`
+	Namespace string `json:"namespace"`
+	Agent     string `json:"agent"`
+}
+
+// TracingConfig is the OTLP exporter configuration the agent runtime traces with.
+type TracingConfig struct {
+	Endpoint           string            `json:"endpoint"`
+	Protocol           string            `json:"protocol"`
+	Insecure           bool              `json:"insecure"`
+	Headers            map[string]string `json:"headers,omitempty"`
+	ServiceName        string            `json:"serviceName"`
+	SamplingRatio      float64           `json:"samplingRatio"`
+	ResourceAttributes map[string]string `json:"resourceAttributes"`
+}
+
+// tracingConfig returns the tracing configuration of an agent with its defaults
+// applied, or nil if tracing is not enabled
+func tracingConfig(agent *asv1alpha1.Agent) *TracingConfig {
+	spec := agent.Spec.Tracing
+	if spec == nil {
+		return nil
+	}
+	cfg := &TracingConfig{
+		Endpoint:      spec.Endpoint,
+		Protocol:      spec.Protocol,
+		Insecure:      spec.Insecure,
+		Headers:       spec.Headers,
+		ServiceName:   spec.ServiceName,
+		SamplingRatio: 1,
+		ResourceAttributes: map[string]string{
+			"k8s.namespace.name": agent.Namespace,
+			"agentstream.agent":  agent.Name,
+		},
+	}
+	if cfg.Protocol == "" {
+		cfg.Protocol = "grpc"
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = agent.Namespace + "." + agent.Name
+	}
+	if spec.SamplingPercentage != nil {
+		cfg.SamplingRatio = float64(*spec.SamplingPercentage) / 100
+	}
+	return cfg
 }
 
 // FSFunctionToolContext represents the context for a function tool.
@@ -301,16 +728,26 @@ func normalizeAgentName(name string) string {
 	return string(result)
 }
 
+// buildAgentContext builds the context of an agent. Tools that cannot be resolved are
+// left out of it, and the first *toolResolutionError is returned along with it
 func (r *AgentReconciler) buildAgentContext(ctx context.Context, agent *asv1alpha1.Agent) (*AgentContext, error) {
 	agentCtx := &AgentContext{}
 	agentCtx.Name = normalizeAgentName(agent.Name)
 	agentCtx.Description = agent.Spec.Description
 	agentCtx.Instruction = agent.Spec.Instruction
 
+	var unresolved error
 	for _, toolName := range agent.Spec.Tools {
 		toolCtx, err := r.buildFSFunctionToolContext(ctx, agent, toolName)
+		var toolErr *toolResolutionError
+		if stderrors.As(err, &toolErr) {
+			if unresolved == nil {
+				unresolved = fmt.Errorf("failed to build tool context for %s: %w", toolName.String(), err)
+			}
+			continue
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to build tool context for %s: %v", toolName.String(), err)
+			return nil, fmt.Errorf("failed to build tool context for %s: %w", toolName.String(), err)
 		}
 		if agentCtx.Tools == nil {
 			agentCtx.Tools = make(map[string]*FSFunctionToolContext)
@@ -324,12 +761,29 @@ func (r *AgentReconciler) buildAgentContext(ctx context.Context, agent *asv1alph
 		}
 	}
 
-	return agentCtx, nil
+	return agentCtx, unresolved
 }
 
 func (r *AgentReconciler) buildFSFunctionToolContext(ctx context.Context, agent *asv1alpha1.Agent, toolName asv1alpha1.NamespacedName) (*FSFunctionToolContext, error) {
+	toolRef := toolName.GetNamespacedName(agent.Namespace)
+	if !namespaceWatched(r.Config.WatchNamespaces, toolRef.Namespace) {
+		return nil, r.toolResolutionFailed(agent, ReasonToolNamespaceNotAllowed, "Tool function %s is in namespace %s, which the operator does not watch", toolName.String(), toolRef.Namespace)
+	}
+	if toolRef.Namespace != agent.Namespace {
+		allowed, err := r.toolGranted(ctx, agent, toolRef)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, r.toolResolutionFailed(agent, ReasonToolAccessDenied, "Tool function %s is not granted to agent %s/%s by a ToolGrant in namespace %s", toolName.String(), agent.Namespace, agent.Name, toolRef.Namespace)
+		}
+	}
+
 	var f fsv1alpha1.Function
-	if err := r.Get(ctx, toolName.GetNamespacedName(agent.Namespace), &f); err != nil {
+	if err := r.Get(ctx, toolRef, &f); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, r.toolResolutionFailed(agent, ReasonToolNotFound, "Tool function %s not found", toolName.String())
+		}
 		return nil, fmt.Errorf("failed to get function %s: %v", toolName.String(), err)
 	}
 
@@ -338,13 +792,19 @@ func (r *AgentReconciler) buildFSFunctionToolContext(ctx context.Context, agent
 	if pkgNamespace == "" {
 		pkgNamespace = f.Namespace
 	}
+	if !namespaceWatched(r.Config.WatchNamespaces, pkgNamespace) {
+		return nil, r.toolResolutionFailed(agent, ReasonToolNamespaceNotAllowed, "Package %s/%s of tool %s is in a namespace the operator does not watch", pkgNamespace, f.Spec.PackageRef.Name, toolName.String())
+	}
 	if err := r.Get(ctx, types.NamespacedName{Name: f.Spec.PackageRef.Name, Namespace: pkgNamespace}, &p); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, r.toolResolutionFailed(agent, ReasonPackageNotFound, "Package %s/%s of tool %s not found", pkgNamespace, f.Spec.PackageRef.Name, toolName.String())
+		}
 		return nil, fmt.Errorf("failed to get package %s: %v", f.Spec.PackageRef.Name, err)
 	}
 
 	module, ok := p.Spec.Modules[f.Spec.Module]
 	if !ok {
-		return nil, fmt.Errorf("module %s not found in package %s", f.Spec.Module, f.Spec.PackageRef.Name)
+		return nil, r.toolResolutionFailed(agent, ReasonModuleNotFound, "Module %s of tool %s not found in package %s", f.Spec.Module, toolName.String(), f.Spec.PackageRef.Name)
 	}
 
 	toolCtx := &FSFunctionToolContext{
@@ -360,7 +820,7 @@ func (r *AgentReconciler) buildFSFunctionToolContext(ctx context.Context, agent
 	}
 
 	if f.Spec.RequestSource.Pulsar == nil {
-		return nil, fmt.Errorf("function %s does not have a request source", f.Name)
+		return nil, r.toolResolutionFailed(agent, ReasonToolRequestSourceMissing, "Tool function %s does not have a request source", toolName.String())
 	}
 
 	toolCtx.RequestSource = f.Spec.RequestSource.Pulsar.Topic
@@ -368,6 +828,62 @@ func (r *AgentReconciler) buildFSFunctionToolContext(ctx context.Context, agent
 	return toolCtx, nil
 }
 
+// toolGranted reports whether a ToolGrant in the namespace of a tool lets the agent use it
+func (r *AgentReconciler) toolGranted(ctx context.Context, agent *asv1alpha1.Agent, tool types.NamespacedName) (bool, error) {
+	var grants asv1alpha1.ToolGrantList
+	if err := r.List(ctx, &grants, client.InNamespace(tool.Namespace)); err != nil {
+		return false, fmt.Errorf("failed to list tool grants in %s: %v", tool.Namespace, err)
+	}
+	for i := range grants.Items {
+		if grants.Items[i].Allows(agent.Namespace, agent.Name, tool.Name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// toolResolutionFailed records a warning event on the agent, counts the failure and
+// returns it as an error
+func (r *AgentReconciler) toolResolutionFailed(agent *asv1alpha1.Agent, reason, messageFmt string, args ...interface{}) error {
+	r.Recorder.Eventf(agent, corev1.EventTypeWarning, reason, messageFmt, args...)
+	toolResolutionFailures.WithLabelValues(agent.Namespace, reason).Inc()
+	return &toolResolutionError{reason: reason, message: fmt.Sprintf(messageFmt, args...)}
+}
+
+// agentsForToolGrant enqueues the agents a ToolGrant may concern: those in the
+// namespaces it grants to that reference a tool of its namespace
+func (r *AgentReconciler) agentsForToolGrant(ctx context.Context, obj client.Object) []reconcile.Request {
+	grant, ok := obj.(*asv1alpha1.ToolGrant)
+	if !ok {
+		return nil
+	}
+	namespaces := map[string]bool{}
+	for _, from := range grant.Spec.From {
+		namespaces[from.Namespace] = true
+	}
+
+	var requests []reconcile.Request
+	for ns := range namespaces {
+		if !namespaceWatched(r.Config.WatchNamespaces, ns) {
+			continue
+		}
+		var agents asv1alpha1.AgentList
+		if err := r.List(ctx, &agents, client.InNamespace(ns)); err != nil {
+			logf.FromContext(ctx).Error(err, "Failed to list agents", "namespace", ns)
+			continue
+		}
+		for _, agent := range agents.Items {
+			for _, tool := range agent.Spec.Tools {
+				if tool.GetNamespacedName(agent.Namespace).Namespace == grant.Namespace {
+					requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&agent)})
+					break
+				}
+			}
+		}
+	}
+	return requests
+}
+
 func convertFunctionStatusToAgentStatus(fs *fsv1alpha1.FunctionStatus) asv1alpha1.AgentStatus {
 	return asv1alpha1.AgentStatus{
 		FunctionStatus: *fs,
@@ -382,9 +898,19 @@ func hasAgentLabel(obj client.Object) bool {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AgentReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&asv1alpha1.Agent{}).
 		Owns(&fsv1alpha1.Function{}, builder.WithPredicates(predicate.NewPredicateFuncs(hasAgentLabel))).
-		Named("agent").
-		Complete(r)
+		// Granting or revoking access to tools resolves them again
+		Watches(&asv1alpha1.ToolGrant{}, handler.EnqueueRequestsFromMapFunc(r.agentsForToolGrant))
+	if r.OperatorConfig.Name != "" {
+		// The defaults of the operator config apply to all agents
+		b = b.Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.agentsForOperatorConfig),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.isOperatorConfig)))
+	}
+	if r.Gateway != nil {
+		// Throttle reports update the status of the agent they are about
+		b = b.WatchesRawSource(source.Channel(r.Gateway.Events(), &handler.EnqueueRequestForObject{}))
+	}
+	return b.Named("agent").Complete(r)
 }
@@ -23,8 +23,12 @@ import (
 	fsv1alpha1 "github.com/FunctionStream/function-stream/operator/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	asv1alpha1 "github.com/agentstream/agentstream/operator/api/v1alpha1"
@@ -70,8 +74,9 @@ var _ = Describe("Agent Controller", func() {
 		It("Should handle agent not found gracefully", func() {
 			By("Reconciling a non-existent agent")
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://localhost:6650",
 					PulsarAuthPlugin: "",
@@ -108,8 +113,9 @@ var _ = Describe("Agent Controller", func() {
 
 			By("Reconciling the created resource")
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://localhost:6650",
 					PulsarAuthPlugin: "",
@@ -129,6 +135,459 @@ var _ = Describe("Agent Controller", func() {
 			Expect(function.Namespace).To(Equal(namespace))
 		})
 
+		It("Should record events for the default response topic and the created Function", func() {
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+				Spec: asv1alpha1.AgentSpec{
+					Description: "A test agent for events",
+					Instruction: "You are a helpful test agent",
+					Model: asv1alpha1.ModelConfig{
+						Model: "gpt-3.5-turbo",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AgentReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Config: Config{
+					PulsarServiceURL: "pulsar://localhost:6650",
+				},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(recorder.Events).To(Receive(ContainSubstring("Normal " + ReasonResponseTopicAssigned)))
+			Expect(recorder.Events).To(Receive(Equal("Normal " + ReasonFunctionCreated + " Created Function " + resourceName)))
+		})
+
+		It("Should record a warning event when a tool is missing", func() {
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+				Spec: asv1alpha1.AgentSpec{
+					Description: "A test agent with a missing tool",
+					Instruction: "You are a helpful test agent",
+					Model: asv1alpha1.ModelConfig{
+						Model: "gpt-3.5-turbo",
+					},
+					ResponseSource: &fsv1alpha1.SourceSpec{
+						Pulsar: &fsv1alpha1.PulsarSourceSpec{
+							Topic: "response-topic",
+						},
+					},
+					Tools: []asv1alpha1.NamespacedName{
+						{Name: "missing-tool"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AgentReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Config: Config{
+					PulsarServiceURL: "pulsar://localhost:6650",
+				},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(recorder.Events).To(Receive(Equal("Warning " + ReasonToolNotFound + " Tool function missing-tool not found")))
+		})
+
+		It("Should delete the Function of an agent suspended by a budget", func() {
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+				Spec: asv1alpha1.AgentSpec{
+					Instruction: "You are a helpful test agent",
+					Model: asv1alpha1.ModelConfig{
+						Model: "gpt-3.5-turbo",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AgentReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Config: Config{
+					PulsarServiceURL: "pulsar://localhost:6650",
+				},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &fsv1alpha1.Function{})).To(Succeed())
+
+			By("Suspending the agent")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, agent)).To(Succeed())
+			agent.Annotations = map[string]string{asv1alpha1.SuspendedByBudgetAnnotation: "team-budget"}
+			Expect(k8sClient.Update(ctx, agent)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &fsv1alpha1.Function{}))).To(BeTrue())
+			Expect(drainEvents(recorder)).To(ContainElement(
+				"Warning " + ReasonSuspended + " Deleted Function " + resourceName + ", budget team-budget is exhausted"))
+		})
+
+		It("Should suspend and resume an agent with spec.suspended", func() {
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+				Spec: asv1alpha1.AgentSpec{
+					Instruction: "You are a helpful test agent",
+					Model: asv1alpha1.ModelConfig{
+						Model: "gpt-3.5-turbo",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AgentReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Config: Config{
+					PulsarServiceURL: "pulsar://localhost:6650",
+				},
+			}
+			reconcileAgent := func() {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			reconcileAgent()
+			Expect(k8sClient.Get(ctx, typeNamespacedName, agent)).To(Succeed())
+			responseTopic := agent.Spec.ResponseSource.Pulsar.Topic
+			Expect(meta.IsStatusConditionFalse(agent.Status.Conditions, asv1alpha1.AgentConditionSuspended)).To(BeTrue())
+
+			By("Suspending the agent")
+			agent.Spec.Suspended = true
+			Expect(k8sClient.Update(ctx, agent)).To(Succeed())
+			reconcileAgent()
+
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &fsv1alpha1.Function{}))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, agent)).To(Succeed())
+			condition := meta.FindStatusCondition(agent.Status.Conditions, asv1alpha1.AgentConditionSuspended)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(ConditionReasonSuspendRequested))
+			Expect(agent.Spec.ResponseSource.Pulsar.Topic).To(Equal(responseTopic))
+
+			By("Resuming the agent")
+			agent.Spec.Suspended = false
+			Expect(k8sClient.Update(ctx, agent)).To(Succeed())
+			reconcileAgent()
+
+			function := &fsv1alpha1.Function{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, function)).To(Succeed())
+			Expect(string(function.Spec.Config["responseSource"].Raw)).To(ContainSubstring(responseTopic))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, agent)).To(Succeed())
+			Expect(meta.IsStatusConditionFalse(agent.Status.Conditions, asv1alpha1.AgentConditionSuspended)).To(BeTrue())
+			Expect(drainEvents(recorder)).To(ContainElement(ContainSubstring("Normal " + ReasonResumed)))
+		})
+
+		It("Should reject tools in namespaces the operator does not watch", func() {
+			otherNamespace := "team-b"
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+				Spec: asv1alpha1.AgentSpec{
+					Instruction: "You are a helpful test agent",
+					Model: asv1alpha1.ModelConfig{
+						Model: "gpt-3.5-turbo",
+					},
+					Tools: []asv1alpha1.NamespacedName{
+						{Name: "weather", Namespace: &otherNamespace},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AgentReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Config: Config{
+					PulsarServiceURL: "pulsar://localhost:6650",
+					WatchNamespaces:  []string{namespace},
+				},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(drainEvents(recorder)).To(ContainElement(
+				"Warning " + ReasonToolNamespaceNotAllowed + " Tool function team-b/weather is in namespace team-b, which the operator does not watch"))
+		})
+
+		It("Should reject tools whose package is in a namespace the operator does not watch", func() {
+			tool := &fsv1alpha1.Function{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "weather-tool",
+					Namespace: namespace,
+				},
+				Spec: fsv1alpha1.FunctionSpec{
+					PackageRef: fsv1alpha1.PackageRef{
+						Name:      "tools",
+						Namespace: "team-b",
+					},
+					Module: "weather",
+					RequestSource: &fsv1alpha1.SourceSpec{
+						Pulsar: &fsv1alpha1.PulsarSourceSpec{
+							Topic: "weather-requests",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, tool)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, tool)).To(Succeed())
+			}()
+
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+				Spec: asv1alpha1.AgentSpec{
+					Instruction: "You are a helpful test agent",
+					Model: asv1alpha1.ModelConfig{
+						Model: "gpt-3.5-turbo",
+					},
+					Tools: []asv1alpha1.NamespacedName{
+						{Name: tool.Name},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AgentReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Config: Config{
+					PulsarServiceURL: "pulsar://localhost:6650",
+					WatchNamespaces:  []string{namespace},
+				},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(drainEvents(recorder)).To(ContainElement(
+				"Warning " + ReasonToolNamespaceNotAllowed + " Package team-b/tools of tool weather-tool is in a namespace the operator does not watch"))
+		})
+
+		It("Should deny tools of other namespaces unless a ToolGrant allows them", func() {
+			toolNamespace := "shared-tools"
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: toolNamespace}}
+			if err := k8sClient.Create(ctx, ns); err != nil {
+				Expect(errors.IsAlreadyExists(err)).To(BeTrue())
+			}
+
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+				Spec: asv1alpha1.AgentSpec{
+					Instruction: "You are a helpful test agent",
+					Model: asv1alpha1.ModelConfig{
+						Model: "gpt-3.5-turbo",
+					},
+					Tools: []asv1alpha1.NamespacedName{
+						{Name: "weather", Namespace: &toolNamespace},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AgentReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Config: Config{
+					PulsarServiceURL: "pulsar://localhost:6650",
+				},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(drainEvents(recorder)).To(ContainElement(ContainSubstring("Warning " + ReasonToolAccessDenied)))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, agent)).To(Succeed())
+			condition := meta.FindStatusCondition(agent.Status.Conditions, asv1alpha1.AgentConditionToolsResolved)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(ReasonToolAccessDenied))
+
+			By("Granting the tool to the namespace of the agent")
+			grant := &asv1alpha1.ToolGrant{
+				ObjectMeta: metav1.ObjectMeta{Name: "weather-grant", Namespace: toolNamespace},
+				Spec: asv1alpha1.ToolGrantSpec{
+					Tools: []string{"weather"},
+					From:  []asv1alpha1.ToolGrantSubject{{Namespace: namespace}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, grant)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, grant)).To(Succeed())
+			}()
+			Expect(controllerReconciler.agentsForToolGrant(ctx, grant)).To(ContainElement(reconcile.Request{NamespacedName: typeNamespacedName}))
+
+			// The grant lets the agent look the tool up, which does not exist
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(drainEvents(recorder)).To(ContainElement(ContainSubstring("Warning " + ReasonToolNotFound)))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, agent)).To(Succeed())
+			condition = meta.FindStatusCondition(agent.Status.Conditions, asv1alpha1.AgentConditionToolsResolved)
+			Expect(condition.Reason).To(Equal(ReasonToolNotFound))
+		})
+
+		It("Should take a tool away from the Function once its ToolGrant is deleted", func() {
+			toolNamespace := "shared-tools"
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: toolNamespace}}
+			if err := k8sClient.Create(ctx, ns); err != nil {
+				Expect(errors.IsAlreadyExists(err)).To(BeTrue())
+			}
+
+			pkg := &fsv1alpha1.Package{
+				ObjectMeta: metav1.ObjectMeta{Name: "tools", Namespace: toolNamespace},
+				Spec: fsv1alpha1.PackageSpec{
+					FunctionType: fsv1alpha1.FunctionType{
+						Cloud: &fsv1alpha1.CloudType{Image: "tools:latest"},
+					},
+					Modules: map[string]fsv1alpha1.Module{
+						"weather": {Description: "Reports the weather"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pkg)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, pkg)).To(Succeed())
+			}()
+			tool := &fsv1alpha1.Function{
+				ObjectMeta: metav1.ObjectMeta{Name: "weather", Namespace: toolNamespace},
+				Spec: fsv1alpha1.FunctionSpec{
+					PackageRef: fsv1alpha1.PackageRef{Name: pkg.Name},
+					Module:     "weather",
+					RequestSource: &fsv1alpha1.SourceSpec{
+						Pulsar: &fsv1alpha1.PulsarSourceSpec{
+							Topic: "weather-requests",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, tool)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, tool)).To(Succeed())
+			}()
+			grant := &asv1alpha1.ToolGrant{
+				ObjectMeta: metav1.ObjectMeta{Name: "weather-grant", Namespace: toolNamespace},
+				Spec: asv1alpha1.ToolGrantSpec{
+					Tools: []string{"weather"},
+					From:  []asv1alpha1.ToolGrantSubject{{Namespace: namespace}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, grant)).To(Succeed())
+
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+				Spec: asv1alpha1.AgentSpec{
+					Instruction: "You are a helpful test agent",
+					Model: asv1alpha1.ModelConfig{
+						Model: "gpt-3.5-turbo",
+					},
+					Tools: []asv1alpha1.NamespacedName{
+						{Name: "weather", Namespace: &toolNamespace},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AgentReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Config: Config{
+					PulsarServiceURL: "pulsar://localhost:6650",
+				},
+			}
+			functionTools := func() map[string]*FSFunctionToolContext {
+				function := &fsv1alpha1.Function{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, function)).To(Succeed())
+				var agentCtx AgentContext
+				Expect(json.Unmarshal(function.Spec.Config["agent"].Raw, &agentCtx)).To(Succeed())
+				return agentCtx.Tools
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(functionTools()).To(HaveKey("weather"))
+
+			By("Revoking the grant")
+			Expect(k8sClient.Delete(ctx, grant)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(functionTools()).NotTo(HaveKey("weather"))
+			Expect(drainEvents(recorder)).To(ContainElement(ContainSubstring("Warning " + ReasonToolAccessDenied)))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, agent)).To(Succeed())
+			condition := meta.FindStatusCondition(agent.Status.Conditions, asv1alpha1.AgentConditionToolsResolved)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(ReasonToolAccessDenied))
+		})
+
 		It("Should handle agent with complex configuration", func() {
 			By("Creating an agent with complex configuration")
 			agent := &asv1alpha1.Agent{
@@ -156,8 +615,9 @@ var _ = Describe("Agent Controller", func() {
 
 			By("Testing configuration building")
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://test:6650",
 					PulsarAuthPlugin: "org.apache.pulsar.client.impl.auth.AuthenticationToken",
@@ -166,7 +626,7 @@ var _ = Describe("Agent Controller", func() {
 			}
 
 			// Test the buildFunctionConfig method directly
-			cfg, err := controllerReconciler.buildFunctionConfig(ctx, agent)
+			cfg, err := controllerReconciler.buildFunctionConfig(ctx, agent, controllerReconciler.Config)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(cfg).To(HaveKey("model"))
 			Expect(cfg).To(HaveKey("pulsarRpc"))
@@ -216,8 +676,9 @@ var _ = Describe("Agent Controller", func() {
 
 			By("Testing configuration building with sources and sinks")
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://localhost:6650",
 					PulsarAuthPlugin: "",
@@ -225,7 +686,7 @@ var _ = Describe("Agent Controller", func() {
 				},
 			}
 
-			cfg, err := controllerReconciler.buildFunctionConfig(ctx, agent)
+			cfg, err := controllerReconciler.buildFunctionConfig(ctx, agent, controllerReconciler.Config)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(cfg).To(HaveKey("model"))
 			Expect(cfg).To(HaveKey("pulsarRpc"))
@@ -256,8 +717,9 @@ var _ = Describe("Agent Controller", func() {
 
 			By("Testing configuration building with post-process")
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://localhost:6650",
 					PulsarAuthPlugin: "",
@@ -265,7 +727,7 @@ var _ = Describe("Agent Controller", func() {
 				},
 			}
 
-			cfg, err := controllerReconciler.buildFunctionConfig(ctx, agent)
+			cfg, err := controllerReconciler.buildFunctionConfig(ctx, agent, controllerReconciler.Config)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(cfg).To(HaveKey("agent"))
 		})
@@ -300,6 +762,32 @@ var _ = Describe("Agent Controller", func() {
 			Expect(hasAgentLabel(obj)).To(BeFalse())
 		})
 
+		It("Should match tool grants by tool, namespace and agent", func() {
+			grant := &asv1alpha1.ToolGrant{
+				Spec: asv1alpha1.ToolGrantSpec{
+					Tools: []string{"weather"},
+					From: []asv1alpha1.ToolGrantSubject{
+						{Namespace: "team-a"},
+						{Namespace: "team-b", Agent: "planner"},
+					},
+				},
+			}
+			Expect(grant.Allows("team-a", "any-agent", "weather")).To(BeTrue())
+			Expect(grant.Allows("team-a", "any-agent", "geocode")).To(BeFalse())
+			Expect(grant.Allows("team-b", "planner", "weather")).To(BeTrue())
+			Expect(grant.Allows("team-b", "other-agent", "weather")).To(BeFalse())
+			Expect(grant.Allows("team-c", "planner", "weather")).To(BeFalse())
+
+			grant.Spec.Tools = nil
+			Expect(grant.Allows("team-a", "any-agent", "geocode")).To(BeTrue())
+		})
+
+		It("Should restrict namespaces to the watched ones", func() {
+			Expect(namespaceWatched(nil, "team-a")).To(BeTrue())
+			Expect(namespaceWatched([]string{"team-a", "tools"}, "tools")).To(BeTrue())
+			Expect(namespaceWatched([]string{"team-a", "tools"}, "team-b")).To(BeFalse())
+		})
+
 		It("Should convert function status to agent status correctly", func() {
 			functionStatus := fsv1alpha1.FunctionStatus{
 				Replicas:           2,
@@ -342,8 +830,9 @@ var _ = Describe("Agent Controller", func() {
 			}
 
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://test:6650",
 					PulsarAuthPlugin: "test-plugin",
@@ -351,7 +840,7 @@ var _ = Describe("Agent Controller", func() {
 				},
 			}
 
-			cfg, err := controllerReconciler.buildFunctionConfig(ctx, agent)
+			cfg, err := controllerReconciler.buildFunctionConfig(ctx, agent, controllerReconciler.Config)
 			Expect(err).NotTo(HaveOccurred())
 
 			// Check that all expected configuration keys are present
@@ -389,8 +878,9 @@ var _ = Describe("Agent Controller", func() {
 			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
 
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://test:6650",
 					PulsarAuthPlugin: "test-plugin",
@@ -429,6 +919,171 @@ var _ = Describe("Agent Controller", func() {
 			Expect(k8sClient.Delete(ctx, agent)).To(Succeed())
 		})
 
+		It("Should set the dead-letter topic in the function configuration", func() {
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-agent-dead-letter",
+					Namespace: "default",
+				},
+				Spec: asv1alpha1.AgentSpec{
+					Description: "A test agent with failed requests",
+					Instruction: "Test instruction",
+					Model: asv1alpha1.ModelConfig{
+						Model: "gpt-4",
+					},
+					ResponseSource: &fsv1alpha1.SourceSpec{
+						Pulsar: &fsv1alpha1.PulsarSourceSpec{
+							Topic: "response-topic",
+						},
+					},
+				},
+			}
+
+			controllerReconciler := &AgentReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+				Config: Config{
+					PulsarServiceURL: "pulsar://test:6650",
+				},
+			}
+
+			By("Using the default topic derived from the agent namespace and name")
+			cfg, err := controllerReconciler.buildFunctionConfig(ctx, agent, controllerReconciler.Config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg).To(HaveKey("deadLetterTopic"))
+			var topic string
+			Expect(json.Unmarshal(cfg["deadLetterTopic"].Raw, &topic)).To(Succeed())
+			Expect(topic).To(Equal("persistent://public/default/dead-letter-default-test-agent-dead-letter"))
+
+			By("Using spec.deadLetterTopic when set")
+			agent.Spec.DeadLetterTopic = "persistent://team/agents/failed-requests"
+			cfg, err = controllerReconciler.buildFunctionConfig(ctx, agent, controllerReconciler.Config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Unmarshal(cfg["deadLetterTopic"].Raw, &topic)).To(Succeed())
+			Expect(topic).To(Equal("persistent://team/agents/failed-requests"))
+		})
+
+		It("Should set the tracing configuration when spec.tracing is set", func() {
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-agent-tracing",
+					Namespace: "default",
+				},
+				Spec: asv1alpha1.AgentSpec{
+					Description: "A traced test agent",
+					Instruction: "Test instruction",
+					Model: asv1alpha1.ModelConfig{
+						Model: "gpt-4",
+					},
+					ResponseSource: &fsv1alpha1.SourceSpec{
+						Pulsar: &fsv1alpha1.PulsarSourceSpec{
+							Topic: "response-topic",
+						},
+					},
+				},
+			}
+
+			controllerReconciler := &AgentReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+				Config: Config{
+					PulsarServiceURL: "pulsar://test:6650",
+				},
+			}
+
+			By("Leaving tracing out of the configuration by default")
+			cfg, err := controllerReconciler.buildFunctionConfig(ctx, agent, controllerReconciler.Config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg).NotTo(HaveKey("tracing"))
+
+			By("Applying the defaults of spec.tracing")
+			agent.Spec.Tracing = &asv1alpha1.TracingSpec{
+				Endpoint: "http://otel-collector:4317",
+				Insecure: true,
+			}
+			cfg, err = controllerReconciler.buildFunctionConfig(ctx, agent, controllerReconciler.Config)
+			Expect(err).NotTo(HaveOccurred())
+			var tracing TracingConfig
+			Expect(json.Unmarshal(cfg["tracing"].Raw, &tracing)).To(Succeed())
+			Expect(tracing.Endpoint).To(Equal("http://otel-collector:4317"))
+			Expect(tracing.Protocol).To(Equal("grpc"))
+			Expect(tracing.Insecure).To(BeTrue())
+			Expect(tracing.ServiceName).To(Equal("default.test-agent-tracing"))
+			Expect(tracing.SamplingRatio).To(Equal(1.0))
+			Expect(tracing.ResourceAttributes).To(HaveKeyWithValue("k8s.namespace.name", "default"))
+
+			By("Converting the sampling percentage to a ratio")
+			percentage := int32(25)
+			agent.Spec.Tracing.SamplingPercentage = &percentage
+			cfg, err = controllerReconciler.buildFunctionConfig(ctx, agent, controllerReconciler.Config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Unmarshal(cfg["tracing"].Raw, &tracing)).To(Succeed())
+			Expect(tracing.SamplingRatio).To(Equal(0.25))
+		})
+
+		It("Should set the limits and throttle topic in the function configuration", func() {
+			rps := int32(5)
+			maxToolCalls := int32(10)
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-agent-limits",
+					Namespace: "default",
+				},
+				Spec: asv1alpha1.AgentSpec{
+					Description: "A test agent with limits",
+					Instruction: "Test instruction",
+					Model: asv1alpha1.ModelConfig{
+						Model: "gpt-4",
+					},
+					ResponseSource: &fsv1alpha1.SourceSpec{
+						Pulsar: &fsv1alpha1.PulsarSourceSpec{
+							Topic: "response-topic",
+						},
+					},
+					Limits: &asv1alpha1.LimitsSpec{
+						RequestsPerSecond:      &rps,
+						MaxToolCallsPerRequest: &maxToolCalls,
+					},
+				},
+			}
+
+			controllerReconciler := &AgentReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+				Config: Config{
+					PulsarServiceURL: "pulsar://test:6650",
+				},
+			}
+
+			cfg, err := controllerReconciler.buildFunctionConfig(ctx, agent, controllerReconciler.Config)
+			Expect(err).NotTo(HaveOccurred())
+			var limits map[string]interface{}
+			Expect(json.Unmarshal(cfg["limits"].Raw, &limits)).To(Succeed())
+			Expect(limits).To(Equal(map[string]interface{}{
+				"requestsPerSecond":      float64(5),
+				"maxToolCallsPerRequest": float64(10),
+				"throttleTopic":          "non-persistent://public/default/throttle-default-test-agent-limits",
+			}))
+
+			By("Keeping the throttling status while the agent has limits")
+			agent.Status.Throttling = &asv1alpha1.ThrottlingStatus{
+				Reason:           "RateLimited",
+				Count:            3,
+				LastThrottleTime: metav1.Now(),
+			}
+			Expect(controllerReconciler.throttlingStatus(agent)).To(Equal(agent.Status.Throttling))
+
+			By("Clearing the throttling status once the limits are removed")
+			agent.Spec.Limits = nil
+			Expect(controllerReconciler.throttlingStatus(agent)).To(BeNil())
+			cfg, err = controllerReconciler.buildFunctionConfig(ctx, agent, controllerReconciler.Config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg).NotTo(HaveKey("limits"))
+		})
+
 		It("Should throw error when ResponseSource.Pulsar is nil", func() {
 			agent := &asv1alpha1.Agent{
 				ObjectMeta: metav1.ObjectMeta{
@@ -453,8 +1108,9 @@ var _ = Describe("Agent Controller", func() {
 			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
 
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://test:6650",
 					PulsarAuthPlugin: "test-plugin",
@@ -503,8 +1159,9 @@ var _ = Describe("Agent Controller", func() {
 			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
 
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://test:6650",
 					PulsarAuthPlugin: "test-plugin",
@@ -547,9 +1204,10 @@ var _ = Describe("Agent Controller", func() {
 			}
 
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
-				Config: Config{},
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+				Config:   Config{},
 			}
 
 			agentCtx, err := controllerReconciler.buildAgentContext(ctx, agent)
@@ -581,9 +1239,10 @@ var _ = Describe("Agent Controller", func() {
 			}
 
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
-				Config: Config{},
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+				Config:   Config{},
 			}
 
 			agentCtx, err := controllerReconciler.buildAgentContext(ctx, agent)
@@ -637,8 +1296,9 @@ var _ = Describe("Agent Controller", func() {
 
 			By("Reconciling with default package configuration")
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://localhost:6650",
 					PulsarAuthPlugin: "",
@@ -690,8 +1350,9 @@ var _ = Describe("Agent Controller", func() {
 
 			By("Reconciling with custom package configuration")
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://localhost:6650",
 					PulsarAuthPlugin: "",
@@ -743,8 +1404,9 @@ var _ = Describe("Agent Controller", func() {
 
 			By("Reconciling with package name only configuration")
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://localhost:6650",
 					PulsarAuthPlugin: "",
@@ -796,8 +1458,9 @@ var _ = Describe("Agent Controller", func() {
 
 			By("Reconciling with empty module configuration")
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://localhost:6650",
 					PulsarAuthPlugin: "",
@@ -875,8 +1538,9 @@ var _ = Describe("Agent Controller", func() {
 			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
 
 			controllerReconciler := &AgentReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 				Config: Config{
 					PulsarServiceURL: "pulsar://localhost:6650",
 					PulsarAuthPlugin: "",
@@ -0,0 +1,311 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	asv1alpha1 "github.com/agentstream/agentstream/operator/api/v1alpha1"
+	"github.com/agentstream/agentstream/operator/internal/observability"
+)
+
+// budgetFinalizer resumes the agents a budget suspended before it is deleted
+const budgetFinalizer = "as.agentstream.github.io/budget"
+
+// Reasons of the events recorded on budgets and the agents they suspend
+const (
+	ReasonBudgetThresholdReached = "BudgetThresholdReached"
+	ReasonBudgetExhausted        = "BudgetExhausted"
+	ReasonBudgetReset            = "BudgetReset"
+	ReasonAgentSuspended         = "AgentSuspended"
+	ReasonAgentResumed           = "AgentResumed"
+)
+
+// defaultBudgetThresholds are the percentages of the limit events are recorded at
+var defaultBudgetThresholds = []int32{80, 100}
+
+// BudgetReconciler reconciles a Budget object
+type BudgetReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// WatchNamespaces are the namespaces the operator is restricted to. Usage of agents
+	// in other namespaces is ignored. All namespaces are watched if it is empty
+	WatchNamespaces []string
+	// now returns the current time. It is replaced in tests
+	now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=as.agentstream.github.io,resources=budgets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=as.agentstream.github.io,resources=budgets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=as.agentstream.github.io,resources=budgets/finalizers,verbs=update
+
+// Reconcile counts the spending of a budget in the current month, records events at
+// its thresholds and suspends or resumes its agents
+func (r *BudgetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	log.Info("Reconciling Budget", "budget", req.NamespacedName)
+
+	var budget asv1alpha1.Budget
+	if err := r.Get(ctx, req.NamespacedName, &budget); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !budget.DeletionTimestamp.IsZero() {
+		if _, err := r.syncSuspendedAgents(ctx, &budget, false); err != nil {
+			return ctrl.Result{}, err
+		}
+		if controllerutil.RemoveFinalizer(&budget, budgetFinalizer) {
+			if err := r.Update(ctx, &budget); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+	if controllerutil.AddFinalizer(&budget, budgetFinalizer) {
+		if err := r.Update(ctx, &budget); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	now := r.currentTime()
+	status := budget.Status.DeepCopy()
+	if period := observability.Period(now); status.Period != period {
+		if status.Period != "" {
+			r.Recorder.Eventf(&budget, corev1.EventTypeNormal, ReasonBudgetReset, "Started counting %s, %s spent in %s", period, spentString(&budget.Spec, status.Spent), status.Period)
+		}
+		*status = asv1alpha1.BudgetStatus{Period: period, SuspendedAgents: status.SuspendedAgents}
+	}
+
+	spent := budgetSpent(&budget.Spec, status.InputTokens, status.OutputTokens)
+	status.Spent = &spent
+	percent := spentPercent(spent, budget.Spec.Limit)
+	for _, threshold := range budgetThresholds(&budget.Spec) {
+		if percent < float64(threshold) || threshold <= status.LastThreshold {
+			continue
+		}
+		status.LastThreshold = threshold
+		reason := ReasonBudgetThresholdReached
+		if threshold >= 100 {
+			reason = ReasonBudgetExhausted
+		}
+		r.Recorder.Eventf(&budget, corev1.EventTypeWarning, reason, "Reached %d%% of the budget: %s of %s spent in %s",
+			threshold, spentString(&budget.Spec, &spent), budget.Spec.Limit.String(), status.Period)
+	}
+
+	suspended, err := r.syncSuspendedAgents(ctx, &budget, budget.Spec.SuspendOnExhaustion && percent >= 100)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	status.SuspendedAgents = suspended
+
+	if !reflect.DeepEqual(&budget.Status, status) {
+		budget.Status = *status
+		if err := r.Status().Update(ctx, &budget); err != nil {
+			if errors.IsConflict(err) {
+				return ctrl.Result{Requeue: true}, nil
+			}
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Start counting the next month on time, even without usage
+	return ctrl.Result{RequeueAfter: nextPeriod(now).Sub(now)}, nil
+}
+
+// RecordUsage adds the usage of agents to the budgets they fall under, and deletes
+// the keys it recorded from usage. It is the observability.UsageRecorder of the
+// usage collector
+func (r *BudgetReconciler) RecordUsage(ctx context.Context, usage map[observability.UsageKey]observability.Usage) error {
+	for key, u := range usage {
+		if !namespaceWatched(r.WatchNamespaces, key.Agent.Namespace) {
+			delete(usage, key)
+			continue
+		}
+		var budgets asv1alpha1.BudgetList
+		if err := r.List(ctx, &budgets, client.InNamespace(key.Agent.Namespace)); err != nil {
+			return fmt.Errorf("failed to list budgets in %s: %v", key.Agent.Namespace, err)
+		}
+		for _, b := range budgets.Items {
+			if b.Spec.Agent != "" && b.Spec.Agent != key.Agent.Name {
+				continue
+			}
+			err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				var budget asv1alpha1.Budget
+				if err := r.Get(ctx, client.ObjectKeyFromObject(&b), &budget); err != nil {
+					return err
+				}
+				// Periods sort like the months they stand for
+				switch {
+				case budget.Status.Period > key.Period:
+					return nil
+				case budget.Status.Period < key.Period:
+					budget.Status = asv1alpha1.BudgetStatus{Period: key.Period, SuspendedAgents: budget.Status.SuspendedAgents}
+				}
+				budget.Status.InputTokens += u.InputTokens
+				budget.Status.OutputTokens += u.OutputTokens
+				return r.Status().Update(ctx, &budget)
+			})
+			if err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to record usage of agent %s in budget %s: %v", key.Agent, b.Name, err)
+			}
+		}
+		delete(usage, key)
+	}
+	return nil
+}
+
+// syncSuspendedAgents suspends the agents of a budget if suspend is set, and resumes
+// the agents it suspended otherwise. It returns the names of the agents the budget
+// keeps suspended
+func (r *BudgetReconciler) syncSuspendedAgents(ctx context.Context, budget *asv1alpha1.Budget, suspend bool) ([]string, error) {
+	var agents asv1alpha1.AgentList
+	if err := r.List(ctx, &agents, client.InNamespace(budget.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list agents in %s: %v", budget.Namespace, err)
+	}
+
+	var suspended []string
+	for i := range agents.Items {
+		agent := &agents.Items[i]
+		inScope := budget.Spec.Agent == "" || budget.Spec.Agent == agent.Name
+		by, annotated := agent.Annotations[asv1alpha1.SuspendedByBudgetAnnotation]
+		switch {
+		case suspend && inScope && !annotated:
+			if agent.Annotations == nil {
+				agent.Annotations = map[string]string{}
+			}
+			agent.Annotations[asv1alpha1.SuspendedByBudgetAnnotation] = budget.Name
+			if err := r.Update(ctx, agent); err != nil {
+				return nil, fmt.Errorf("failed to suspend agent %s: %v", agent.Name, err)
+			}
+			r.Recorder.Eventf(agent, corev1.EventTypeWarning, ReasonAgentSuspended, "Suspended because budget %s is exhausted", budget.Name)
+			suspended = append(suspended, agent.Name)
+		case suspend && inScope && by == budget.Name:
+			suspended = append(suspended, agent.Name)
+		case annotated && by == budget.Name:
+			delete(agent.Annotations, asv1alpha1.SuspendedByBudgetAnnotation)
+			if err := r.Update(ctx, agent); err != nil {
+				return nil, fmt.Errorf("failed to resume agent %s: %v", agent.Name, err)
+			}
+			r.Recorder.Eventf(agent, corev1.EventTypeNormal, ReasonAgentResumed, "Resumed, budget %s is no longer exhausted", budget.Name)
+		}
+	}
+	sort.Strings(suspended)
+	return suspended, nil
+}
+
+func (r *BudgetReconciler) currentTime() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// budgetThresholds returns the thresholds of a budget in ascending order
+func budgetThresholds(spec *asv1alpha1.BudgetSpec) []int32 {
+	thresholds := defaultBudgetThresholds
+	if len(spec.Thresholds) > 0 {
+		thresholds = append([]int32(nil), spec.Thresholds...)
+		sort.Slice(thresholds, func(i, j int) bool { return thresholds[i] < thresholds[j] })
+	}
+	return thresholds
+}
+
+// budgetSpent returns the tokens used, or their price if the budget is counted in
+// currency
+func budgetSpent(spec *asv1alpha1.BudgetSpec, inputTokens, outputTokens int64) resource.Quantity {
+	if spec.Unit != asv1alpha1.BudgetUnitCurrency || spec.Pricing == nil {
+		return *resource.NewQuantity(inputTokens+outputTokens, resource.DecimalSI)
+	}
+	price := (float64(inputTokens)*spec.Pricing.InputPerMillionTokens.AsApproximateFloat64() +
+		float64(outputTokens)*spec.Pricing.OutputPerMillionTokens.AsApproximateFloat64()) / 1e6
+	// Cents are precise enough to compare against a limit, and keep the status readable
+	return resource.MustParse(fmt.Sprintf("%.2f", price))
+}
+
+// spentPercent returns the percentage of the limit spent
+func spentPercent(spent, limit resource.Quantity) float64 {
+	if limit.IsZero() {
+		return 100
+	}
+	return spent.AsApproximateFloat64() / limit.AsApproximateFloat64() * 100
+}
+
+// spentString formats a spent amount with the currency of the budget
+func spentString(spec *asv1alpha1.BudgetSpec, spent *resource.Quantity) string {
+	amount := "0"
+	if spent != nil {
+		amount = spent.String()
+	}
+	if spec.Unit == asv1alpha1.BudgetUnitCurrency && spec.Pricing != nil && spec.Pricing.Currency != "" {
+		return amount + " " + spec.Pricing.Currency
+	}
+	if spec.Unit == asv1alpha1.BudgetUnitCurrency {
+		return amount
+	}
+	return amount + " tokens"
+}
+
+// nextPeriod returns the start of the month after t (UTC)
+func nextPeriod(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// budgetsForAgent enqueues the budgets in the namespace of an agent, so new agents
+// are suspended by budgets that are already exhausted
+func (r *BudgetReconciler) budgetsForAgent(ctx context.Context, obj client.Object) []reconcile.Request {
+	var budgets asv1alpha1.BudgetList
+	if err := r.List(ctx, &budgets, client.InNamespace(obj.GetNamespace())); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list budgets", "namespace", obj.GetNamespace())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, b := range budgets.Items {
+		if b.Spec.Agent == "" || b.Spec.Agent == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: b.Namespace, Name: b.Name}})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BudgetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&asv1alpha1.Budget{}).
+		Watches(&asv1alpha1.Agent{}, handler.EnqueueRequestsFromMapFunc(r.budgetsForAgent)).
+		Named("budget").
+		Complete(r)
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	asv1alpha1 "github.com/agentstream/agentstream/operator/api/v1alpha1"
+	"github.com/agentstream/agentstream/operator/internal/observability"
+)
+
+var _ = Describe("Budget Controller", func() {
+	Context("When reconciling a resource", func() {
+		const namespace = "default"
+
+		ctx := context.Background()
+		budgetName := types.NamespacedName{Name: "test-budget", Namespace: namespace}
+		agentName := types.NamespacedName{Name: "budget-agent", Namespace: namespace}
+		now := time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC)
+
+		var recorder *record.FakeRecorder
+		var reconciler *BudgetReconciler
+
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(100)
+			reconciler = &BudgetReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				now:      func() time.Time { return now },
+			}
+
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{Name: agentName.Name, Namespace: namespace},
+				Spec: asv1alpha1.AgentSpec{
+					Instruction: "You are a helpful test agent",
+					Model:       asv1alpha1.ModelConfig{Model: "gemini-2.0-flash"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			budget := &asv1alpha1.Budget{}
+			if err := k8sClient.Get(ctx, budgetName, budget); err == nil {
+				Expect(k8sClient.Delete(ctx, budget)).To(Succeed())
+				// Let the finalizer resume the agents
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: budgetName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			agent := &asv1alpha1.Agent{}
+			if err := k8sClient.Get(ctx, agentName, agent); err == nil {
+				Expect(k8sClient.Delete(ctx, agent)).To(Succeed())
+			}
+		})
+
+		It("Should count usage, record threshold events and suspend the agent once exhausted", func() {
+			budget := &asv1alpha1.Budget{
+				ObjectMeta: metav1.ObjectMeta{Name: budgetName.Name, Namespace: namespace},
+				Spec: asv1alpha1.BudgetSpec{
+					Agent:               agentName.Name,
+					Unit:                asv1alpha1.BudgetUnitTokens,
+					Limit:               resource.MustParse("1000"),
+					SuspendOnExhaustion: true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, budget)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: budgetName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Recording usage below the first threshold")
+			key := observability.UsageKey{Agent: agentName, Period: observability.Period(now)}
+			Expect(reconciler.RecordUsage(ctx, map[observability.UsageKey]observability.Usage{
+				key: {InputTokens: 400, OutputTokens: 100},
+			})).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: budgetName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, budgetName, budget)).To(Succeed())
+			Expect(budget.Status.Period).To(Equal("2025-03"))
+			Expect(budget.Status.Spent.String()).To(Equal("500"))
+			Expect(budget.Status.LastThreshold).To(BeZero())
+			Expect(budget.Finalizers).To(ContainElement(budgetFinalizer))
+
+			By("Recording usage that exhausts the budget")
+			Expect(reconciler.RecordUsage(ctx, map[observability.UsageKey]observability.Usage{
+				key: {InputTokens: 400, OutputTokens: 200},
+			})).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: budgetName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, budgetName, budget)).To(Succeed())
+			Expect(budget.Status.Spent.String()).To(Equal("1100"))
+			Expect(budget.Status.LastThreshold).To(Equal(int32(100)))
+			Expect(budget.Status.SuspendedAgents).To(Equal([]string{agentName.Name}))
+
+			agent := &asv1alpha1.Agent{}
+			Expect(k8sClient.Get(ctx, agentName, agent)).To(Succeed())
+			Expect(agent.Annotations).To(HaveKeyWithValue(asv1alpha1.SuspendedByBudgetAnnotation, budgetName.Name))
+
+			events := drainEvents(recorder)
+			Expect(events).To(ContainElement(ContainSubstring(ReasonBudgetThresholdReached)))
+			Expect(events).To(ContainElement(ContainSubstring(ReasonBudgetExhausted)))
+			Expect(events).To(ContainElement(ContainSubstring(ReasonAgentSuspended)))
+
+			By("Raising the limit")
+			budget.Spec.Limit = resource.MustParse("2000")
+			Expect(k8sClient.Update(ctx, budget)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: budgetName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, agentName, agent)).To(Succeed())
+			Expect(agent.Annotations).NotTo(HaveKey(asv1alpha1.SuspendedByBudgetAnnotation))
+			Expect(k8sClient.Get(ctx, budgetName, budget)).To(Succeed())
+			Expect(budget.Status.SuspendedAgents).To(BeEmpty())
+			Expect(drainEvents(recorder)).To(ContainElement(ContainSubstring(ReasonAgentResumed)))
+		})
+
+		It("Should start counting again in a new month", func() {
+			budget := &asv1alpha1.Budget{
+				ObjectMeta: metav1.ObjectMeta{Name: budgetName.Name, Namespace: namespace},
+				Spec: asv1alpha1.BudgetSpec{
+					Limit: resource.MustParse("1000"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, budget)).To(Succeed())
+			Expect(reconciler.RecordUsage(ctx, map[observability.UsageKey]observability.Usage{
+				{Agent: agentName, Period: "2025-02"}: {InputTokens: 900},
+			})).To(Succeed())
+
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: budgetName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC).Sub(now)))
+
+			Expect(k8sClient.Get(ctx, budgetName, budget)).To(Succeed())
+			Expect(budget.Status.Period).To(Equal("2025-03"))
+			Expect(budget.Status.InputTokens).To(BeZero())
+			Expect(drainEvents(recorder)).To(ContainElement(ContainSubstring(ReasonBudgetReset)))
+
+			By("Ignoring usage of the previous month reported late")
+			Expect(reconciler.RecordUsage(ctx, map[observability.UsageKey]observability.Usage{
+				{Agent: agentName, Period: "2025-02"}: {InputTokens: 100},
+			})).To(Succeed())
+			Expect(k8sClient.Get(ctx, budgetName, budget)).To(Succeed())
+			Expect(budget.Status.InputTokens).To(BeZero())
+		})
+
+		It("Should not count usage of agents outside the budget", func() {
+			budget := &asv1alpha1.Budget{
+				ObjectMeta: metav1.ObjectMeta{Name: budgetName.Name, Namespace: namespace},
+				Spec: asv1alpha1.BudgetSpec{
+					Agent: agentName.Name,
+					Limit: resource.MustParse("1000"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, budget)).To(Succeed())
+
+			Expect(reconciler.RecordUsage(ctx, map[observability.UsageKey]observability.Usage{
+				{Agent: types.NamespacedName{Namespace: namespace, Name: "other-agent"}, Period: "2025-03"}: {InputTokens: 100},
+			})).To(Succeed())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(budget), budget)).To(Succeed())
+			Expect(budget.Status.InputTokens).To(BeZero())
+		})
+
+		It("Should record usage once when recording part of it fails", func() {
+			budget := &asv1alpha1.Budget{
+				ObjectMeta: metav1.ObjectMeta{Name: budgetName.Name, Namespace: namespace},
+				Spec: asv1alpha1.BudgetSpec{
+					Limit: resource.MustParse("1000"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, budget)).To(Succeed())
+
+			recorded := observability.UsageKey{Agent: agentName, Period: "2025-03"}
+			failing := observability.UsageKey{Agent: types.NamespacedName{Namespace: "unreachable", Name: "other-agent"}, Period: "2025-03"}
+			usage := map[observability.UsageKey]observability.Usage{
+				recorded: {InputTokens: 400},
+				failing:  {InputTokens: 100},
+			}
+			reconciler.Client = failingListClient{Client: k8sClient, namespace: failing.Agent.Namespace}
+			Expect(reconciler.RecordUsage(ctx, usage)).NotTo(Succeed())
+			Expect(usage).To(HaveKey(failing))
+
+			By("Recording the usage left once the failure is gone")
+			reconciler.Client = k8sClient
+			Expect(reconciler.RecordUsage(ctx, usage)).To(Succeed())
+			Expect(usage).To(BeEmpty())
+
+			Expect(k8sClient.Get(ctx, budgetName, budget)).To(Succeed())
+			Expect(budget.Status.InputTokens).To(Equal(int64(400)))
+		})
+	})
+
+	Context("Helper functions", func() {
+		It("Should price usage for currency budgets", func() {
+			spec := &asv1alpha1.BudgetSpec{
+				Unit: asv1alpha1.BudgetUnitCurrency,
+				Pricing: &asv1alpha1.BudgetPricing{
+					InputPerMillionTokens:  resource.MustParse("0.10"),
+					OutputPerMillionTokens: resource.MustParse("0.40"),
+					Currency:               "USD",
+				},
+			}
+			spent := budgetSpent(spec, 10_000_000, 5_000_000)
+			Expect(spent.AsApproximateFloat64()).To(BeNumerically("~", 3.0))
+			Expect(spentString(spec, &spent)).To(Equal("3 USD"))
+
+			tokens := budgetSpent(&asv1alpha1.BudgetSpec{}, 300, 200)
+			Expect(tokens.Value()).To(Equal(int64(500)))
+		})
+
+		It("Should compute the spent percentage", func() {
+			Expect(spentPercent(resource.MustParse("80"), resource.MustParse("100"))).To(BeNumerically("~", 80))
+			Expect(spentPercent(resource.MustParse("0"), resource.MustParse("0"))).To(Equal(float64(100)))
+		})
+
+		It("Should sort thresholds and default them", func() {
+			Expect(budgetThresholds(&asv1alpha1.BudgetSpec{})).To(Equal([]int32{80, 100}))
+			Expect(budgetThresholds(&asv1alpha1.BudgetSpec{Thresholds: []int32{100, 50, 90}})).To(Equal([]int32{50, 90, 100}))
+		})
+
+		It("Should find the start of the next period", func() {
+			Expect(nextPeriod(time.Date(2025, time.December, 31, 23, 0, 0, 0, time.UTC))).
+				To(Equal(time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)))
+		})
+	})
+})
+
+// drainEvents returns the events recorded so far
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case e := <-recorder.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+// failingListClient fails to list objects in a namespace
+type failingListClient struct {
+	client.Client
+	namespace string
+}
+
+func (c failingListClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.Namespace == c.namespace {
+		return fmt.Errorf("namespace %s is unreachable", c.namespace)
+	}
+	return c.Client.List(ctx, list, opts...)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	fsv1alpha1 "github.com/FunctionStream/function-stream/operator/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Phases agents are counted by in the agentstream_agents metric
+const (
+	PhasePending = "Pending"
+	PhaseReady   = "Ready"
+	PhaseFailed  = "Failed"
+	// PhaseSuspended is the phase of agents whose Function is deleted until they are resumed
+	PhaseSuspended = "Suspended"
+)
+
+var (
+	agentsByPhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "agentstream_agents",
+		Help: "Number of agents by namespace and phase",
+	}, []string{"namespace", "phase"})
+
+	toolResolutionFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentstream_tool_resolution_failures_total",
+		Help: "Number of times a tool of an agent could not be resolved, by reason",
+	}, []string{"namespace", "reason"})
+
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "agentstream_agent_reconcile_duration_seconds",
+		Help:    "Duration of agent reconciles",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"namespace", "agent"})
+
+	agentTools = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "agentstream_agent_tools",
+		Help: "Number of tools referenced by an agent",
+	}, []string{"namespace", "agent"})
+
+	// The time since the last sync is time() minus this timestamp, which keeps the
+	// metric correct between reconciles
+	lastFunctionSync = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "agentstream_agent_last_function_sync_timestamp_seconds",
+		Help: "Unix time of the last reconcile that brought the Function of an agent up to date",
+	}, []string{"namespace", "agent"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(agentsByPhase, toolResolutionFailures, reconcileDuration, agentTools, lastFunctionSync)
+}
+
+// phaseTracker keeps the phase of every agent so agentsByPhase can be kept as counts
+type phaseTracker struct {
+	mu     sync.Mutex
+	phases map[types.NamespacedName]string
+}
+
+var agentPhases = &phaseTracker{phases: map[types.NamespacedName]string{}}
+
+// set records the phase of an agent
+func (t *phaseTracker) set(agent types.NamespacedName, phase string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	old, ok := t.phases[agent]
+	if ok && old == phase {
+		return
+	}
+	if ok {
+		agentsByPhase.WithLabelValues(agent.Namespace, old).Dec()
+	}
+	t.phases[agent] = phase
+	agentsByPhase.WithLabelValues(agent.Namespace, phase).Inc()
+}
+
+// remove forgets a deleted agent
+func (t *phaseTracker) remove(agent types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if old, ok := t.phases[agent]; ok {
+		agentsByPhase.WithLabelValues(agent.Namespace, old).Dec()
+		delete(t.phases, agent)
+	}
+}
+
+// functionPhase derives the phase of an agent from the status of its Function
+func functionPhase(status *fsv1alpha1.FunctionStatus) string {
+	if status.Replicas > 0 && status.ReadyReplicas >= status.Replicas {
+		return PhaseReady
+	}
+	return PhasePending
+}
+
+// observeReconcile records the duration of an agent reconcile that started at start
+func observeReconcile(agent types.NamespacedName, start time.Time) {
+	reconcileDuration.WithLabelValues(agent.Namespace, agent.Name).Observe(time.Since(start).Seconds())
+}
+
+// forgetAgentMetrics removes the series of a deleted agent
+func forgetAgentMetrics(agent types.NamespacedName) {
+	agentPhases.remove(agent)
+	reconcileDuration.DeleteLabelValues(agent.Namespace, agent.Name)
+	agentTools.DeleteLabelValues(agent.Namespace, agent.Name)
+	lastFunctionSync.DeleteLabelValues(agent.Namespace, agent.Name)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	fsv1alpha1 "github.com/FunctionStream/function-stream/operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Agent metrics", func() {
+	const namespace = "metrics-test"
+
+	It("Should count agents by namespace and phase", func() {
+		tracker := &phaseTracker{phases: map[types.NamespacedName]string{}}
+		first := types.NamespacedName{Namespace: namespace, Name: "first"}
+		second := types.NamespacedName{Namespace: namespace, Name: "second"}
+
+		tracker.set(first, PhasePending)
+		tracker.set(second, PhasePending)
+		Expect(testutil.ToFloat64(agentsByPhase.WithLabelValues(namespace, PhasePending))).To(Equal(2.0))
+
+		By("Moving an agent to another phase")
+		tracker.set(first, PhaseReady)
+		tracker.set(first, PhaseReady)
+		Expect(testutil.ToFloat64(agentsByPhase.WithLabelValues(namespace, PhasePending))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(agentsByPhase.WithLabelValues(namespace, PhaseReady))).To(Equal(1.0))
+
+		By("Removing deleted agents")
+		tracker.remove(first)
+		tracker.remove(second)
+		tracker.remove(second)
+		Expect(testutil.ToFloat64(agentsByPhase.WithLabelValues(namespace, PhasePending))).To(Equal(0.0))
+		Expect(testutil.ToFloat64(agentsByPhase.WithLabelValues(namespace, PhaseReady))).To(Equal(0.0))
+	})
+
+	It("Should derive the phase from the Function status", func() {
+		Expect(functionPhase(&fsv1alpha1.FunctionStatus{})).To(Equal(PhasePending))
+		Expect(functionPhase(&fsv1alpha1.FunctionStatus{Replicas: 2, ReadyReplicas: 1})).To(Equal(PhasePending))
+		Expect(functionPhase(&fsv1alpha1.FunctionStatus{Replicas: 2, ReadyReplicas: 2})).To(Equal(PhaseReady))
+	})
+})
@@ -0,0 +1,159 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	asv1alpha1 "github.com/agentstream/agentstream/operator/api/v1alpha1"
+)
+
+// Keys of the operator config ConfigMap. Each overrides the Config field of the
+// same name while it is set
+const (
+	OperatorConfigAgentPackage           = "agentPackage"
+	OperatorConfigAgentModule            = "agentModule"
+	OperatorConfigDefaultModel           = "defaultModel"
+	OperatorConfigPulsarServiceURL       = "pulsarServiceUrl"
+	OperatorConfigPulsarAuthPlugin       = "pulsarAuthPlugin"
+	OperatorConfigPulsarAuthParams       = "pulsarAuthParams"
+	OperatorConfigResponseTopicPattern   = "responseTopicPattern"
+	OperatorConfigDeadLetterTopicPattern = "deadLetterTopicPattern"
+	OperatorConfigThrottleTopicPattern   = "throttleTopicPattern"
+)
+
+// Default topic patterns. {namespace} and {name} are replaced by those of the agent,
+// {id} by a random ID
+const (
+	DefaultResponseTopicPattern   = "non-persistent://public/default/response-source-{name}-{id}"
+	DefaultDeadLetterTopicPattern = "persistent://public/default/dead-letter-{namespace}-{name}"
+	DefaultThrottleTopicPattern   = "non-persistent://public/default/throttle-{namespace}-{name}"
+)
+
+// topicName expands a topic pattern for an agent
+func topicName(pattern string, agent *asv1alpha1.Agent, id string) string {
+	return strings.NewReplacer("{namespace}", agent.Namespace, "{name}", agent.Name, "{id}", id).Replace(pattern)
+}
+
+// validateTopicPattern checks that a pattern names a topic of its own for every agent
+func validateTopicPattern(key, pattern string, placeholders ...string) error {
+	for _, p := range placeholders {
+		if !strings.Contains(pattern, p) {
+			return fmt.Errorf("%s %q does not contain %s", key, pattern, p)
+		}
+	}
+	return nil
+}
+
+// applyOperatorConfig returns the config with the values set in the data of the
+// operator config ConfigMap, and why the keys it ignores are ignored
+func applyOperatorConfig(config Config, data map[string]string) (Config, []string, error) {
+	fields := map[string]*string{
+		OperatorConfigAgentPackage:           &config.AgentPackage,
+		OperatorConfigAgentModule:            &config.AgentModule,
+		OperatorConfigDefaultModel:           &config.DefaultModel,
+		OperatorConfigPulsarServiceURL:       &config.PulsarServiceURL,
+		OperatorConfigPulsarAuthPlugin:       &config.PulsarAuthPlugin,
+		OperatorConfigPulsarAuthParams:       &config.PulsarAuthParams,
+		OperatorConfigResponseTopicPattern:   &config.ResponseTopicPattern,
+		OperatorConfigDeadLetterTopicPattern: &config.DeadLetterTopicPattern,
+		OperatorConfigThrottleTopicPattern:   &config.ThrottleTopicPattern,
+	}
+	var ignored []string
+	for key, value := range data {
+		field, ok := fields[key]
+		if !ok {
+			ignored = append(ignored, fmt.Sprintf("unknown key %s", key))
+			continue
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			*field = value
+		}
+	}
+	sort.Strings(ignored)
+
+	if config.ResponseTopicPattern != "" {
+		if err := validateTopicPattern(OperatorConfigResponseTopicPattern, config.ResponseTopicPattern, "{id}"); err != nil {
+			return config, ignored, err
+		}
+	}
+	if config.DeadLetterTopicPattern != "" {
+		if err := validateTopicPattern(OperatorConfigDeadLetterTopicPattern, config.DeadLetterTopicPattern, "{namespace}", "{name}"); err != nil {
+			return config, ignored, err
+		}
+	}
+	if config.ThrottleTopicPattern != "" {
+		if err := validateTopicPattern(OperatorConfigThrottleTopicPattern, config.ThrottleTopicPattern, "{namespace}", "{name}"); err != nil {
+			return config, ignored, err
+		}
+	}
+	return config, ignored, nil
+}
+
+// operatorConfig returns the config agents are reconciled with: Config, overridden by
+// the operator config ConfigMap if it is set and exists. Keys that are ignored are
+// reported in a warning event on the ConfigMap
+func (r *AgentReconciler) operatorConfig(ctx context.Context) (Config, error) {
+	if r.OperatorConfig.Name == "" {
+		return r.Config, nil
+	}
+	var cm corev1.ConfigMap
+	if err := r.Get(ctx, r.OperatorConfig, &cm); err != nil {
+		if errors.IsNotFound(err) {
+			return r.Config, nil
+		}
+		return r.Config, fmt.Errorf("failed to get operator config %s: %v", r.OperatorConfig, err)
+	}
+	config, ignored, err := applyOperatorConfig(r.Config, cm.Data)
+	if len(ignored) > 0 {
+		logf.FromContext(ctx).Info("Ignoring keys of the operator config", "configMap", r.OperatorConfig, "keys", ignored)
+		r.Recorder.Eventf(&cm, corev1.EventTypeWarning, ReasonOperatorConfigKeyIgnored, "Ignored %s", strings.Join(ignored, ", "))
+	}
+	if err != nil {
+		return r.Config, fmt.Errorf("invalid operator config %s: %v", r.OperatorConfig, err)
+	}
+	return config, nil
+}
+
+// isOperatorConfig reports whether an object is the operator config ConfigMap
+func (r *AgentReconciler) isOperatorConfig(obj client.Object) bool {
+	return client.ObjectKeyFromObject(obj) == r.OperatorConfig
+}
+
+// agentsForOperatorConfig enqueues all agents, since the defaults of the operator
+// config apply to any of them
+func (r *AgentReconciler) agentsForOperatorConfig(ctx context.Context, obj client.Object) []reconcile.Request {
+	var agents asv1alpha1.AgentList
+	if err := r.List(ctx, &agents); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list agents")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(agents.Items))
+	for _, agent := range agents.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&agent)})
+	}
+	return requests
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	asv1alpha1 "github.com/agentstream/agentstream/operator/api/v1alpha1"
+	"github.com/agentstream/agentstream/operator/internal/observability"
+)
+
+var _ = Describe("Operator Config", func() {
+	Context("When reconciling agents with an operator config", func() {
+		const namespace = "default"
+
+		ctx := context.Background()
+		configName := types.NamespacedName{Name: "operator-config", Namespace: namespace}
+		agentName := types.NamespacedName{Name: "test-agent-operator-config", Namespace: namespace}
+
+		var reconciler *AgentReconciler
+
+		BeforeEach(func() {
+			reconciler = &AgentReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+				Config: Config{
+					PulsarServiceURL: "pulsar://flags:6650",
+					AgentPackage:     "default.agent",
+				},
+				OperatorConfig: configName,
+			}
+
+			agent := &asv1alpha1.Agent{
+				ObjectMeta: metav1.ObjectMeta{Name: agentName.Name, Namespace: namespace},
+				Spec: asv1alpha1.AgentSpec{
+					Description: "Agent without a model",
+					Instruction: "You are a helpful test agent",
+				},
+			}
+			Expect(k8sClient.Create(ctx, agent)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			cm := &corev1.ConfigMap{}
+			if err := k8sClient.Get(ctx, configName, cm); err == nil {
+				Expect(k8sClient.Delete(ctx, cm)).To(Succeed())
+			}
+			agent := &asv1alpha1.Agent{}
+			if err := k8sClient.Get(ctx, agentName, agent); err == nil {
+				Expect(k8sClient.Delete(ctx, agent)).To(Succeed())
+			}
+		})
+
+		It("Should use the flags while the ConfigMap does not exist", func() {
+			config, err := reconciler.operatorConfig(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).To(Equal(reconciler.Config))
+
+			agent := &asv1alpha1.Agent{}
+			Expect(k8sClient.Get(ctx, agentName, agent)).To(Succeed())
+			_, err = reconciler.buildFunctionConfig(ctx, agent, config)
+			Expect(err).To(MatchError(ContainSubstring("no default model")))
+		})
+
+		It("Should apply the defaults of the ConfigMap", func() {
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: configName.Name, Namespace: namespace},
+				Data: map[string]string{
+					OperatorConfigDefaultModel:           "gemini-2.0-flash",
+					OperatorConfigResponseTopicPattern:   "non-persistent://agents/{namespace}/response-{name}-{id}",
+					OperatorConfigDeadLetterTopicPattern: "persistent://agents/{namespace}/dead-letter-{name}",
+				},
+			}
+			Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+
+			config, err := reconciler.operatorConfig(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			agent := &asv1alpha1.Agent{}
+			Expect(k8sClient.Get(ctx, agentName, agent)).To(Succeed())
+			cfg, err := reconciler.buildFunctionConfig(ctx, agent, config)
+			Expect(err).NotTo(HaveOccurred())
+
+			var model asv1alpha1.ModelConfig
+			Expect(json.Unmarshal(cfg["model"].Raw, &model)).To(Succeed())
+			Expect(model.Model).To(Equal("gemini-2.0-flash"))
+
+			var topic string
+			Expect(json.Unmarshal(cfg["deadLetterTopic"].Raw, &topic)).To(Succeed())
+			Expect(topic).To(Equal("persistent://agents/default/dead-letter-test-agent-operator-config"))
+			Expect(agent.Spec.ResponseSource.Pulsar.Topic).To(HavePrefix("non-persistent://agents/default/response-test-agent-operator-config-"))
+
+			By("Enqueueing the agents when it changes")
+			Expect(reconciler.agentsForOperatorConfig(ctx, cm)).To(ContainElement(reconcile.Request{NamespacedName: agentName}))
+			Expect(reconciler.isOperatorConfig(cm)).To(BeTrue())
+			Expect(reconciler.isOperatorConfig(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: namespace},
+			})).To(BeFalse())
+		})
+
+		It("Should move the agent runtimes and the operator to the Pulsar connection of the ConfigMap", func() {
+			conn, err := observability.NewConnection(observability.ConnectionSettings{ServiceURL: reconciler.Config.PulsarServiceURL})
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+			reconciler.Connection = conn
+			flagsClient := conn.Client()
+
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: configName.Name, Namespace: namespace},
+				Data: map[string]string{
+					OperatorConfigDefaultModel:     "gemini-2.0-flash",
+					OperatorConfigPulsarServiceURL: "pulsar://runtime:6650",
+				},
+			}
+			Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+
+			config, err := reconciler.operatorConfig(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			agent := &asv1alpha1.Agent{}
+			Expect(k8sClient.Get(ctx, agentName, agent)).To(Succeed())
+			cfg, err := reconciler.buildFunctionConfig(ctx, agent, config)
+			Expect(err).NotTo(HaveOccurred())
+			var pulsarCfg map[string]string
+			Expect(json.Unmarshal(cfg["pulsarRpc"].Raw, &pulsarCfg)).To(Succeed())
+			Expect(pulsarCfg["serviceUrl"]).To(Equal("pulsar://runtime:6650"))
+
+			Expect(reconciler.syncConnection(config)).To(Succeed())
+			runtimeClient := conn.Client()
+			Expect(runtimeClient).NotTo(BeNil())
+			Expect(runtimeClient).NotTo(BeIdenticalTo(flagsClient))
+
+			By("Keeping the client while the connection is unchanged")
+			Expect(reconciler.syncConnection(config)).To(Succeed())
+			Expect(conn.Client()).To(BeIdenticalTo(runtimeClient))
+		})
+
+		It("Should ignore unknown keys with a warning event", func() {
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: configName.Name, Namespace: namespace},
+				Data: map[string]string{
+					OperatorConfigDefaultModel: "gemini-2.0-flash",
+					"agentPackages":            "default.agent",
+				},
+			}
+			Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+
+			config, err := reconciler.operatorConfig(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.DefaultModel).To(Equal("gemini-2.0-flash"))
+
+			recorder := reconciler.Recorder.(*record.FakeRecorder)
+			Expect(drainEvents(recorder)).To(ContainElement("Warning " + ReasonOperatorConfigKeyIgnored +
+				" Ignored unknown key agentPackages"))
+		})
+
+		It("Should fail reconciles while the ConfigMap is invalid", func() {
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: configName.Name, Namespace: namespace},
+				Data: map[string]string{
+					OperatorConfigDeadLetterTopicPattern: "persistent://public/default/dead-letters",
+				},
+			}
+			Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: agentName})
+			Expect(err).To(MatchError(ContainSubstring("invalid operator config")))
+			recorder := reconciler.Recorder.(*record.FakeRecorder)
+			Expect(drainEvents(recorder)).To(ContainElement(ContainSubstring(ReasonInvalidOperatorConfig)))
+		})
+	})
+
+	Context("Helper functions", func() {
+		It("Should override only the keys that are set", func() {
+			config, ignored, err := applyOperatorConfig(Config{AgentPackage: "default.agent", AgentModule: "agent"}, map[string]string{
+				OperatorConfigAgentModule:  "agent_v2",
+				OperatorConfigAgentPackage: " ",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ignored).To(BeEmpty())
+			Expect(config.AgentPackage).To(Equal("default.agent"))
+			Expect(config.AgentModule).To(Equal("agent_v2"))
+		})
+
+		It("Should ignore unknown keys and reject patterns that are not unique per agent", func() {
+			config, ignored, err := applyOperatorConfig(Config{PulsarAuthParams: "token:flags"}, map[string]string{
+				"agentPackages":                "default.agent",
+				"pulsarAuthToken":              "token:config",
+				OperatorConfigPulsarAuthParams: "token:config",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.PulsarAuthParams).To(Equal("token:config"))
+			Expect(ignored).To(Equal([]string{"unknown key agentPackages", "unknown key pulsarAuthToken"}))
+
+			_, _, err = applyOperatorConfig(Config{}, map[string]string{
+				OperatorConfigResponseTopicPattern: "non-persistent://public/default/response-{name}",
+			})
+			Expect(err).To(MatchError(ContainSubstring("{id}")))
+
+			_, _, err = applyOperatorConfig(Config{}, map[string]string{
+				OperatorConfigThrottleTopicPattern: "non-persistent://public/default/throttle-{name}",
+			})
+			Expect(err).To(MatchError(ContainSubstring("{namespace}")))
+		})
+
+		It("Should expand topic patterns", func() {
+			agent := &asv1alpha1.Agent{ObjectMeta: metav1.ObjectMeta{Name: "my-agent", Namespace: "team-a"}}
+			Expect(deadLetterTopic(agent, "")).To(Equal("persistent://public/default/dead-letter-team-a-my-agent"))
+			Expect(throttleTopic(agent, "")).To(Equal("non-persistent://public/default/throttle-team-a-my-agent"))
+			Expect(topicName(DefaultResponseTopicPattern, agent, "1234")).To(Equal("non-persistent://public/default/response-source-my-agent-1234"))
+		})
+	})
+})
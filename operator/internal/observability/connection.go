@@ -0,0 +1,140 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package observability
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ConnectionSettings are the Pulsar service and authentication the operator
+// consumes the topics of agents through
+type ConnectionSettings struct {
+	ServiceURL string
+	AuthPlugin string
+	AuthParams string
+}
+
+// newClient creates a client for the settings, or returns nil if no service URL is set
+func (s ConnectionSettings) newClient() (pulsar.Client, error) {
+	if s.ServiceURL == "" {
+		return nil, nil
+	}
+	opts := pulsar.ClientOptions{URL: s.ServiceURL}
+	if s.AuthPlugin != "" {
+		auth, err := pulsar.NewAuthentication(s.AuthPlugin, s.AuthParams)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Pulsar authentication: %v", err)
+		}
+		opts.Authentication = auth
+	}
+	client, err := pulsar.NewClient(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Pulsar client for %s: %v", s.ServiceURL, err)
+	}
+	return client, nil
+}
+
+// Connection is the Pulsar client shared by the gateway and the usage collector. It
+// is rebuilt when its settings change, and they move their consumers to the new client
+type Connection struct {
+	// updateMu keeps updates in order while the listeners move to the new client
+	updateMu sync.Mutex
+
+	mu        sync.Mutex
+	settings  ConnectionSettings
+	client    pulsar.Client
+	listeners []func(pulsar.Client)
+	closed    bool
+}
+
+// NewConnection creates a connection with the given settings. It has no client if
+// no service URL is set
+func NewConnection(settings ConnectionSettings) (*Connection, error) {
+	client, err := settings.newClient()
+	if err != nil {
+		return nil, err
+	}
+	return &Connection{settings: settings, client: client}, nil
+}
+
+// Client returns the current client, or nil if no service URL is set
+func (c *Connection) Client() pulsar.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.client
+}
+
+// onChange registers a function called with the new client whenever it changes. The
+// previous client is closed once all of them returned
+func (c *Connection) onChange(listener func(pulsar.Client)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, listener)
+}
+
+// Update rebuilds the client if the settings changed. The previous client is kept if
+// the new one cannot be created
+func (c *Connection) Update(settings ConnectionSettings) error {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+
+	c.mu.Lock()
+	if c.closed || c.settings == settings {
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+
+	client, err := settings.newClient()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	old := c.client
+	c.settings = settings
+	c.client = client
+	listeners := append([]func(pulsar.Client){}, c.listeners...)
+	c.mu.Unlock()
+	logf.Log.WithName("pulsar-connection").Info("Pulsar connection changed", "serviceUrl", settings.ServiceURL)
+
+	// The listeners stop their consumers before the client they use is closed
+	for _, listener := range listeners {
+		listener(client)
+	}
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// Close closes the client. The connection is not rebuilt afterwards
+func (c *Connection) Close() {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package observability
+
+import (
+	"github.com/apache/pulsar-client-go/pulsar"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Pulsar connection", func() {
+	It("Should rebuild the client and notify its users when the settings change", func() {
+		conn, err := NewConnection(ConnectionSettings{})
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		Expect(conn.Client()).To(BeNil())
+
+		var notified []pulsar.Client
+		conn.onChange(func(client pulsar.Client) {
+			notified = append(notified, client)
+		})
+
+		settings := ConnectionSettings{ServiceURL: "pulsar://localhost:6650"}
+		Expect(conn.Update(settings)).To(Succeed())
+		client := conn.Client()
+		Expect(client).NotTo(BeNil())
+		Expect(notified).To(HaveLen(1))
+		Expect(notified[0]).To(BeIdenticalTo(client))
+
+		By("Keeping the client while the settings are unchanged")
+		Expect(conn.Update(settings)).To(Succeed())
+		Expect(conn.Client()).To(BeIdenticalTo(client))
+		Expect(notified).To(HaveLen(1))
+
+		By("Keeping the client if the new one cannot be created")
+		Expect(conn.Update(ConnectionSettings{ServiceURL: "pulsar://localhost:6650", AuthPlugin: "unknown"})).NotTo(Succeed())
+		Expect(conn.Client()).To(BeIdenticalTo(client))
+		Expect(notified).To(HaveLen(1))
+
+		By("Dropping the client once no service URL is set")
+		Expect(conn.Update(ConnectionSettings{})).To(Succeed())
+		Expect(conn.Client()).To(BeNil())
+		Expect(notified).To(HaveLen(2))
+		Expect(notified[1]).To(BeNil())
+	})
+
+	It("Should keep the targets of the gateway while the connection has no client", func() {
+		conn, err := NewConnection(ConnectionSettings{})
+		Expect(err).NotTo(HaveOccurred())
+		g := NewGateway(conn)
+		agent := types.NamespacedName{Namespace: "default", Name: "unconnected-agent"}
+
+		Expect(g.Watch(agent, Target{ThrottleTopic: "non-persistent://public/default/throttle-default-unconnected-agent"})).To(Succeed())
+		Expect(g.targets).To(HaveKey(agent))
+		Expect(g.watches).To(BeEmpty())
+
+		g.Forget(agent)
+		Expect(g.targets).To(BeEmpty())
+	})
+})
@@ -0,0 +1,139 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package observability
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Message properties the correlator reads
+const (
+	RequestIDProperty     = "request_id"
+	ResponseTopicProperty = "response_topic"
+	ErrorProperty         = "error"
+	InputTokensProperty   = "input_tokens"
+	OutputTokensProperty  = "output_tokens"
+)
+
+// Reasons a request counts as failed
+const (
+	ErrorReasonError   = "error"
+	ErrorReasonTimeout = "timeout"
+)
+
+// pendingRequest is a request waiting for its response
+type pendingRequest struct {
+	publishTime   time.Time
+	responseTopic string
+}
+
+// correlator matches the requests and responses of one agent by request_id and
+// records their metrics
+type correlator struct {
+	agent   types.NamespacedName
+	timeout time.Duration
+	// sinkTopic is where responses to requests without a response_topic are sent
+	sinkTopic string
+
+	mu      sync.Mutex
+	pending map[string]pendingRequest
+}
+
+func newCorrelator(agent types.NamespacedName, timeout time.Duration, sinkTopic string) *correlator {
+	return &correlator{
+		agent:     agent,
+		timeout:   timeout,
+		sinkTopic: sinkTopic,
+		pending:   map[string]pendingRequest{},
+	}
+}
+
+// request records a request. It returns the topic the response is expected on, the
+// response_topic of the request or else the sink topic, or an empty string if the
+// request cannot be correlated
+func (c *correlator) request(properties map[string]string, publishTime time.Time) string {
+	requestsTotal.WithLabelValues(c.agent.Namespace, c.agent.Name).Inc()
+
+	id := properties[RequestIDProperty]
+	topic := properties[ResponseTopicProperty]
+	if topic == "" {
+		topic = c.sinkTopic
+	}
+	if id == "" || topic == "" {
+		return ""
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending[id] = pendingRequest{publishTime: publishTime, responseTopic: topic}
+	return topic
+}
+
+// response records a response and the latency of its request
+func (c *correlator) response(properties map[string]string, publishTime time.Time) {
+	c.mu.Lock()
+	req, ok := c.pending[properties[RequestIDProperty]]
+	if ok {
+		delete(c.pending, properties[RequestIDProperty])
+	}
+	c.mu.Unlock()
+	// Responses to requests sent before the watch started, or to other agents
+	// replying on the same topic, are not counted
+	if !ok {
+		return
+	}
+
+	labels := []string{c.agent.Namespace, c.agent.Name}
+	requestDuration.WithLabelValues(labels...).Observe(publishTime.Sub(req.publishTime).Seconds())
+	if properties[ErrorProperty] != "" {
+		requestErrors.WithLabelValues(c.agent.Namespace, c.agent.Name, ErrorReasonError).Inc()
+	}
+	for property, tokenType := range map[string]string{InputTokensProperty: "input", OutputTokensProperty: "output"} {
+		if tokens, err := strconv.ParseFloat(properties[property], 64); err == nil && tokens > 0 {
+			tokensTotal.WithLabelValues(c.agent.Namespace, c.agent.Name, tokenType).Add(tokens)
+		}
+	}
+}
+
+// expire counts the requests without a response for longer than the timeout as
+// failed and forgets them
+func (c *correlator) expire(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, req := range c.pending {
+		if now.Sub(req.publishTime) > c.timeout {
+			requestErrors.WithLabelValues(c.agent.Namespace, c.agent.Name, ErrorReasonTimeout).Inc()
+			delete(c.pending, id)
+		}
+	}
+}
+
+// awaited reports whether any pending request expects its response on the topic
+func (c *correlator) awaited(topic string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, req := range c.pending {
+		if req.responseTopic == topic {
+			return true
+		}
+	}
+	return false
+}
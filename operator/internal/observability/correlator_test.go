@@ -0,0 +1,99 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package observability
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Request correlator", func() {
+	var (
+		agent types.NamespacedName
+		c     *correlator
+		start time.Time
+	)
+
+	BeforeEach(func() {
+		agent = types.NamespacedName{Namespace: "default", Name: CurrentSpecReport().LeafNodeText}
+		c = newCorrelator(agent, time.Minute, "")
+		start = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	It("Should match responses to requests by request_id", func() {
+		topic := c.request(map[string]string{
+			RequestIDProperty:     "1",
+			ResponseTopicProperty: "response-topic",
+		}, start)
+		Expect(topic).To(Equal("response-topic"))
+		Expect(c.awaited("response-topic")).To(BeTrue())
+
+		c.response(map[string]string{
+			RequestIDProperty:    "1",
+			InputTokensProperty:  "120",
+			OutputTokensProperty: "30",
+		}, start.Add(2*time.Second))
+
+		Expect(c.awaited("response-topic")).To(BeFalse())
+		Expect(testutil.ToFloat64(requestsTotal.WithLabelValues(agent.Namespace, agent.Name))).To(Equal(1.0))
+		Expect(testutil.CollectAndCount(requestDuration)).To(BeNumerically(">=", 1))
+		Expect(testutil.ToFloat64(tokensTotal.WithLabelValues(agent.Namespace, agent.Name, "input"))).To(Equal(120.0))
+		Expect(testutil.ToFloat64(tokensTotal.WithLabelValues(agent.Namespace, agent.Name, "output"))).To(Equal(30.0))
+		Expect(testutil.ToFloat64(requestErrors.WithLabelValues(agent.Namespace, agent.Name, ErrorReasonError))).To(Equal(0.0))
+	})
+
+	It("Should count error responses", func() {
+		c.request(map[string]string{RequestIDProperty: "1", ResponseTopicProperty: "response-topic"}, start)
+		c.response(map[string]string{RequestIDProperty: "1", ErrorProperty: "boom"}, start.Add(time.Second))
+
+		Expect(testutil.ToFloat64(requestErrors.WithLabelValues(agent.Namespace, agent.Name, ErrorReasonError))).To(Equal(1.0))
+	})
+
+	It("Should count requests without a response in time as timed out", func() {
+		c.request(map[string]string{RequestIDProperty: "1", ResponseTopicProperty: "response-topic"}, start)
+		c.request(map[string]string{RequestIDProperty: "2", ResponseTopicProperty: "response-topic"}, start.Add(50*time.Second))
+
+		c.expire(start.Add(90 * time.Second))
+
+		Expect(testutil.ToFloat64(requestErrors.WithLabelValues(agent.Namespace, agent.Name, ErrorReasonTimeout))).To(Equal(1.0))
+		Expect(c.awaited("response-topic")).To(BeTrue())
+	})
+
+	It("Should count requests that cannot be correlated without waiting on them", func() {
+		Expect(c.request(map[string]string{RequestIDProperty: "1"}, start)).To(BeEmpty())
+		Expect(c.request(map[string]string{}, start)).To(BeEmpty())
+
+		c.expire(start.Add(time.Hour))
+
+		Expect(testutil.ToFloat64(requestsTotal.WithLabelValues(agent.Namespace, agent.Name))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(requestErrors.WithLabelValues(agent.Namespace, agent.Name, ErrorReasonTimeout))).To(Equal(0.0))
+	})
+
+	It("Should expect responses to requests without a response_topic on the sink topic", func() {
+		c = newCorrelator(agent, time.Minute, "sink-topic")
+		Expect(c.request(map[string]string{RequestIDProperty: "1"}, start)).To(Equal("sink-topic"))
+		Expect(c.request(map[string]string{RequestIDProperty: "2", ResponseTopicProperty: "response-topic"}, start)).To(Equal("response-topic"))
+		Expect(c.awaited("sink-topic")).To(BeTrue())
+
+		c.response(map[string]string{RequestIDProperty: "1"}, start.Add(time.Second))
+		Expect(c.awaited("sink-topic")).To(BeFalse())
+	})
+})
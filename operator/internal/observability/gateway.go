@@ -0,0 +1,476 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package observability collects per-agent request metrics by consuming the
+// request topics of agents and the response topics named in their requests, and
+// the throttle reports agent runtimes publish when spec.limits throttles requests.
+package observability
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// DefaultResponseTimeout is how long a request may wait for its response before
+	// it counts as failed
+	DefaultResponseTimeout = 5 * time.Minute
+
+	// maxResponseTopics bounds the response topics consumed per agent, as callers
+	// like ascli rpc use a new topic for every request
+	maxResponseTopics = 100
+
+	// expireInterval is how often requests without a response are expired
+	expireInterval = 10 * time.Second
+
+	// eventBuffer is the number of reconcile events that may wait for the controller
+	eventBuffer = 100
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentstream_agent_requests_total",
+		Help: "Number of requests sent to an agent",
+	}, []string{"namespace", "agent"})
+
+	requestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentstream_agent_request_errors_total",
+		Help: "Number of requests that failed, with an error response or without a response in time",
+	}, []string{"namespace", "agent", "reason"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "agentstream_agent_request_duration_seconds",
+		Help:    "Time between the publishing of a request and of its response",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"namespace", "agent"})
+
+	tokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentstream_agent_tokens_total",
+		Help: "Number of model tokens reported in the responses of an agent, by type",
+	}, []string{"namespace", "agent", "type"})
+
+	throttledRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agentstream_agent_throttled_requests_total",
+		Help: "Number of requests the runtime of an agent reported as throttled, by reason",
+	}, []string{"namespace", "agent", "reason"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(requestsTotal, requestErrors, requestDuration, tokensTotal, throttledRequests)
+}
+
+// Target is what the gateway consumes for an agent. Request metrics are collected
+// if RequestTopic is set, and throttle reports if ThrottleTopic is set
+type Target struct {
+	RequestTopic string
+	// ResponseTopic and SinkTopic are consumed with the request topic, so that no
+	// response on them is missed. Responses to requests without a response_topic
+	// property are expected on the sink topic
+	ResponseTopic   string
+	SinkTopic       string
+	ResponseTimeout time.Duration
+	ThrottleTopic   string
+}
+
+// Gateway consumes the topics of the agents it watches and exports their request
+// metrics on the controller-runtime metrics registry. The watches move to a new
+// client when the connection changes, and wait while it has none
+type Gateway struct {
+	conn *Connection
+
+	mu      sync.Mutex
+	client  pulsar.Client
+	targets map[types.NamespacedName]Target
+	watches map[types.NamespacedName]*watch
+	stopped bool
+
+	stateMu    sync.Mutex
+	throttling map[types.NamespacedName]ThrottleReport
+	events     chan event.GenericEvent
+}
+
+// NewGateway creates a gateway consuming through the given connection
+func NewGateway(conn *Connection) *Gateway {
+	g := &Gateway{
+		conn:       conn,
+		client:     conn.Client(),
+		targets:    map[types.NamespacedName]Target{},
+		watches:    map[types.NamespacedName]*watch{},
+		throttling: map[types.NamespacedName]ThrottleReport{},
+		events:     make(chan event.GenericEvent, eventBuffer),
+	}
+	conn.onChange(g.reconnect)
+	return g
+}
+
+// Start implements manager.Runnable. The watches are added by the reconciler and
+// stopped when ctx is done
+func (g *Gateway) Start(ctx context.Context) error {
+	<-ctx.Done()
+	g.mu.Lock()
+	watches := g.watches
+	g.watches = map[types.NamespacedName]*watch{}
+	g.stopped = true
+	g.mu.Unlock()
+	for _, w := range watches {
+		w.stop()
+	}
+	g.conn.Close()
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so that only one
+// replica consumes the topics
+func (g *Gateway) NeedLeaderElection() bool {
+	return true
+}
+
+// Watch starts consuming the topics of an agent, or restarts if its target changed.
+// The reconciler calls Watch and Forget for an agent one at a time
+func (g *Gateway) Watch(agent types.NamespacedName, target Target) error {
+	if target.ResponseTimeout <= 0 {
+		target.ResponseTimeout = DefaultResponseTimeout
+	}
+
+	g.mu.Lock()
+	old, ok := g.watches[agent]
+	if g.targets[agent] == target && (ok || g.client == nil) {
+		g.mu.Unlock()
+		return nil
+	}
+	g.targets[agent] = target
+	delete(g.watches, agent)
+	client := g.client
+	g.mu.Unlock()
+
+	// Subscribing and closing consumers go over the network, so they are done
+	// without holding the lock. The old watch is stopped first, as the subscriptions
+	// of both would have the same name
+	if old != nil {
+		old.stop()
+	}
+	if target.RequestTopic == "" {
+		forgetRequestMetrics(agent)
+	}
+	if target.ThrottleTopic == "" {
+		g.forgetThrottling(agent)
+	}
+	// The watch starts once the connection has a client
+	if client == nil {
+		return nil
+	}
+
+	w, err := startWatch(g, client, agent, target)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	// The watch is left to reconnect if the client changed meanwhile
+	if g.stopped || g.client != client {
+		g.mu.Unlock()
+		w.stop()
+		return nil
+	}
+	g.watches[agent] = w
+	g.mu.Unlock()
+	return nil
+}
+
+// Forget stops consuming the topics of an agent and removes its metrics
+func (g *Gateway) Forget(agent types.NamespacedName) {
+	g.mu.Lock()
+	w, ok := g.watches[agent]
+	delete(g.watches, agent)
+	delete(g.targets, agent)
+	g.mu.Unlock()
+	if ok {
+		w.stop()
+	}
+	forgetRequestMetrics(agent)
+	g.forgetThrottling(agent)
+}
+
+// reconnect moves the watches to a new client, or stops them until the connection
+// has a client again if it is nil. The watches are stopped before the previous
+// client is closed
+func (g *Gateway) reconnect(client pulsar.Client) {
+	g.mu.Lock()
+	watches := g.watches
+	g.watches = map[types.NamespacedName]*watch{}
+	g.client = client
+	targets := make(map[types.NamespacedName]Target, len(g.targets))
+	for agent, target := range g.targets {
+		targets[agent] = target
+	}
+	stopped := g.stopped
+	g.mu.Unlock()
+
+	for _, w := range watches {
+		w.stop()
+	}
+	if client == nil || stopped {
+		return
+	}
+	for agent, target := range targets {
+		w, err := startWatch(g, client, agent, target)
+		if err != nil {
+			// Agents are all reconciled when the operator config changes, which starts
+			// the watch again
+			logf.Log.WithName("request-metrics").Error(err, "Failed to consume the topics of the agent after the Pulsar connection changed", "agent", agent)
+			continue
+		}
+		g.mu.Lock()
+		// Watch or Forget may have run for the agent meanwhile
+		if g.stopped || g.client != client || g.targets[agent] != target || g.watches[agent] != nil {
+			g.mu.Unlock()
+			w.stop()
+			continue
+		}
+		g.watches[agent] = w
+		g.mu.Unlock()
+	}
+}
+
+// forgetRequestMetrics removes the request metric series of an agent
+func forgetRequestMetrics(agent types.NamespacedName) {
+	requestsTotal.DeleteLabelValues(agent.Namespace, agent.Name)
+	requestDuration.DeleteLabelValues(agent.Namespace, agent.Name)
+	requestErrors.DeletePartialMatch(prometheus.Labels{"namespace": agent.Namespace, "agent": agent.Name})
+	tokensTotal.DeletePartialMatch(prometheus.Labels{"namespace": agent.Namespace, "agent": agent.Name})
+}
+
+// forgetThrottling removes the last throttle report and the throttle metric series
+// of an agent
+func (g *Gateway) forgetThrottling(agent types.NamespacedName) {
+	g.stateMu.Lock()
+	delete(g.throttling, agent)
+	g.stateMu.Unlock()
+	throttledRequests.DeletePartialMatch(prometheus.Labels{"namespace": agent.Namespace, "agent": agent.Name})
+}
+
+// watch consumes the request topic of one agent and the response topics its
+// pending requests wait on, and the throttle topic of the agent
+type watch struct {
+	gateway      *Gateway
+	agent        types.NamespacedName
+	target       Target
+	client       pulsar.Client
+	subscription string
+	correlator   *correlator
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu        sync.Mutex
+	consumers map[string]*topicConsumer
+}
+
+// topicConsumer is a consumer with the function that stops its receive loop
+type topicConsumer struct {
+	consumer pulsar.Consumer
+	cancel   context.CancelFunc
+}
+
+// close stops the receive loop and closes the consumer
+func (c *topicConsumer) close() {
+	c.cancel()
+	c.consumer.Close()
+}
+
+func startWatch(g *Gateway, client pulsar.Client, agent types.NamespacedName, target Target) (*watch, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &watch{
+		gateway: g,
+		agent:   agent,
+		target:  target,
+		client:  client,
+		// Exclusive subscriptions need a name of their own per agent, as agents
+		// may share a request topic
+		subscription: fmt.Sprintf("agentstream-metrics-%s-%s", agent.Namespace, agent.Name),
+		correlator:   newCorrelator(agent, target.ResponseTimeout, target.SinkTopic),
+		ctx:          ctx,
+		cancel:       cancel,
+		consumers:    map[string]*topicConsumer{},
+	}
+
+	handlers := map[string]func(pulsar.Message){}
+	if target.RequestTopic != "" {
+		handlers[target.RequestTopic] = w.onRequest
+		for _, topic := range []string{target.ResponseTopic, target.SinkTopic} {
+			if _, ok := handlers[topic]; topic != "" && !ok {
+				handlers[topic] = w.onResponse
+			}
+		}
+	}
+	if target.ThrottleTopic != "" {
+		handlers[target.ThrottleTopic] = w.onThrottle
+	}
+	for topic, handle := range handlers {
+		if err := w.consume(topic, handle, time.Time{}); err != nil {
+			w.stop()
+			return nil, err
+		}
+	}
+
+	w.wg.Add(1)
+	go w.expireLoop()
+	return w, nil
+}
+
+// consume subscribes to a topic and hands its messages to handle until the watch
+// stops. Persistent topics are consumed from since if it is set
+func (w *watch) consume(topic string, handle func(pulsar.Message), since time.Time) error {
+	// A non-durable subscription keeps no backlog while the operator is down
+	consumer, err := w.client.Subscribe(pulsar.ConsumerOptions{
+		Topic:                       topic,
+		SubscriptionName:            w.subscription,
+		Type:                        pulsar.Exclusive,
+		SubscriptionMode:            pulsar.NonDurable,
+		SubscriptionInitialPosition: pulsar.SubscriptionPositionLatest,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %v", topic, err)
+	}
+	// Messages of non-persistent topics are only delivered to connected consumers, so
+	// they cannot be consumed from the past
+	if !since.IsZero() && !strings.HasPrefix(topic, "non-persistent://") {
+		if err := consumer.SeekByTime(since); err != nil {
+			logf.Log.WithName("request-metrics").Error(err, "Failed to seek response topic, earlier responses are missed", "agent", w.agent, "topic", topic)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(w.ctx)
+	w.mu.Lock()
+	w.consumers[topic] = &topicConsumer{consumer: consumer, cancel: cancel}
+	w.mu.Unlock()
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		log := logf.Log.WithName("request-metrics").WithValues("agent", w.agent, "topic", topic)
+		for {
+			msg, err := consumer.Receive(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Error(err, "Failed to receive message")
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Second):
+				}
+				continue
+			}
+			handle(msg)
+			if err := consumer.Ack(msg); err != nil {
+				log.Error(err, "Failed to acknowledge message")
+			}
+		}
+	}()
+	return nil
+}
+
+// onRequest records a request and starts consuming the topic its response is sent
+// to, from the time of the request so that a response sent before the subscription
+// is not missed
+func (w *watch) onRequest(msg pulsar.Message) {
+	topic := w.correlator.request(msg.Properties(), msg.PublishTime())
+	if topic == "" {
+		return
+	}
+
+	w.mu.Lock()
+	_, ok := w.consumers[topic]
+	full := len(w.consumers) >= maxResponseTopics
+	w.mu.Unlock()
+	if ok || full {
+		return
+	}
+	if err := w.consume(topic, w.onResponse, msg.PublishTime()); err != nil {
+		logf.Log.WithName("request-metrics").Error(err, "Failed to consume response topic", "agent", w.agent)
+	}
+}
+
+func (w *watch) onResponse(msg pulsar.Message) {
+	w.correlator.response(msg.Properties(), msg.PublishTime())
+}
+
+func (w *watch) onThrottle(msg pulsar.Message) {
+	report, err := parseThrottleReport(msg.Payload(), msg.PublishTime())
+	if err != nil {
+		logf.Log.WithName("request-metrics").Error(err, "Skipping throttle report", "agent", w.agent)
+		return
+	}
+	w.gateway.recordThrottle(w.ctx.Done(), w.agent, report)
+}
+
+// expireLoop expires requests without a response and closes the response topics
+// no request waits on anymore
+func (w *watch) expireLoop() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.correlator.expire(now)
+
+			w.mu.Lock()
+			for topic, consumer := range w.consumers {
+				if !w.consumedUpFront(topic) && !w.correlator.awaited(topic) {
+					consumer.close()
+					delete(w.consumers, topic)
+				}
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// consumedUpFront reports whether a topic is consumed for as long as the watch runs
+func (w *watch) consumedUpFront(topic string) bool {
+	switch topic {
+	case w.target.RequestTopic, w.target.ResponseTopic, w.target.SinkTopic, w.target.ThrottleTopic:
+		return true
+	}
+	return false
+}
+
+// stop closes all consumers of the watch
+func (w *watch) stop() {
+	w.cancel()
+	w.wg.Wait()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for topic, consumer := range w.consumers {
+		consumer.consumer.Close()
+		delete(w.consumers, topic)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package observability
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// Reasons the agent runtime throttles requests for
+const (
+	ThrottleReasonRateLimited           = "RateLimited"
+	ThrottleReasonConcurrencyLimited    = "ConcurrencyLimited"
+	ThrottleReasonToolCallLimitExceeded = "ToolCallLimitExceeded"
+	ThrottleReasonTokenLimitExceeded    = "TokenLimitExceeded"
+)
+
+// ThrottleReport is published by the agent runtime to its throttle topic after it
+// throttled requests because of spec.limits
+type ThrottleReport struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+	Count   int64  `json:"count"`
+	// Time is when the report was published
+	Time time.Time `json:"-"`
+}
+
+// parseThrottleReport decodes a report published at publishTime
+func parseThrottleReport(payload []byte, publishTime time.Time) (ThrottleReport, error) {
+	var report ThrottleReport
+	if err := json.Unmarshal(payload, &report); err != nil {
+		return ThrottleReport{}, fmt.Errorf("invalid throttle report: %v", err)
+	}
+	if report.Reason == "" {
+		return ThrottleReport{}, fmt.Errorf("invalid throttle report: no reason")
+	}
+	report.Time = publishTime
+	return report, nil
+}
+
+// recordThrottle keeps the last report of an agent and asks for the agent to be
+// reconciled, so its status shows the throttling
+func (g *Gateway) recordThrottle(done <-chan struct{}, agent types.NamespacedName, report ThrottleReport) {
+	throttledRequests.WithLabelValues(agent.Namespace, agent.Name, report.Reason).Add(float64(report.Count))
+
+	g.stateMu.Lock()
+	g.throttling[agent] = report
+	g.stateMu.Unlock()
+
+	obj := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: agent.Namespace, Name: agent.Name}}
+	select {
+	case g.events <- event.GenericEvent{Object: obj}:
+	case <-done:
+	}
+}
+
+// Throttling returns the last throttle report of an agent since it is watched
+func (g *Gateway) Throttling(agent types.NamespacedName) (ThrottleReport, bool) {
+	g.stateMu.Lock()
+	defer g.stateMu.Unlock()
+	report, ok := g.throttling[agent]
+	return report, ok
+}
+
+// Events delivers an event for every agent with a new throttle report, to be used as
+// a source of the agent controller
+func (g *Gateway) Events() <-chan event.GenericEvent {
+	return g.events
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package observability
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Throttle reports", func() {
+	var publishTime = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	It("Should parse the reports of the agent runtime", func() {
+		report, err := parseThrottleReport([]byte(`{"reason": "RateLimited", "message": "5 requests waited for the rate limit", "count": 5}`), publishTime)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report).To(Equal(ThrottleReport{
+			Reason:  ThrottleReasonRateLimited,
+			Message: "5 requests waited for the rate limit",
+			Count:   5,
+			Time:    publishTime,
+		}))
+
+		_, err = parseThrottleReport([]byte(`{"count": 5}`), publishTime)
+		Expect(err).To(HaveOccurred())
+		_, err = parseThrottleReport([]byte(`not json`), publishTime)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should keep the last report of an agent and ask for it to be reconciled", func() {
+		conn, err := NewConnection(ConnectionSettings{})
+		Expect(err).NotTo(HaveOccurred())
+		g := NewGateway(conn)
+		agent := types.NamespacedName{Namespace: "default", Name: "throttled-agent"}
+		done := make(chan struct{})
+		defer close(done)
+
+		_, ok := g.Throttling(agent)
+		Expect(ok).To(BeFalse())
+
+		g.recordThrottle(done, agent, ThrottleReport{Reason: ThrottleReasonRateLimited, Count: 3, Time: publishTime})
+		g.recordThrottle(done, agent, ThrottleReport{Reason: ThrottleReasonTokenLimitExceeded, Count: 1, Time: publishTime.Add(10 * time.Second)})
+
+		report, ok := g.Throttling(agent)
+		Expect(ok).To(BeTrue())
+		Expect(report.Reason).To(Equal(ThrottleReasonTokenLimitExceeded))
+		Expect(testutil.ToFloat64(throttledRequests.WithLabelValues(agent.Namespace, agent.Name, ThrottleReasonRateLimited))).To(Equal(3.0))
+
+		Expect(g.Events()).To(HaveLen(2))
+		ev := <-g.Events()
+		Expect(ev.Object.GetNamespace()).To(Equal(agent.Namespace))
+		Expect(ev.Object.GetName()).To(Equal(agent.Name))
+
+		By("Forgetting the reports once the agent has no limits")
+		g.forgetThrottling(agent)
+		_, ok = g.Throttling(agent)
+		Expect(ok).To(BeFalse())
+	})
+})
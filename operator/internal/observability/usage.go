@@ -0,0 +1,231 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultUsageTopic is the topic agent runtimes report their model usage on
+	DefaultUsageTopic = "persistent://public/default/agentstream-usage"
+
+	// usageSubscription is durable, so usage reported while the operator is down is
+	// still counted
+	usageSubscription = "agentstream-operator-usage"
+
+	// usageFlushInterval is how often the collected usage is recorded
+	usageFlushInterval = 10 * time.Second
+)
+
+// UsageReport is published by the agent runtime to the usage topic after every request
+type UsageReport struct {
+	Namespace    string `json:"namespace"`
+	Agent        string `json:"agent"`
+	Model        string `json:"model,omitempty"`
+	InputTokens  int64  `json:"inputTokens"`
+	OutputTokens int64  `json:"outputTokens"`
+}
+
+// UsageKey identifies the usage of an agent in a month
+type UsageKey struct {
+	Agent types.NamespacedName
+	// Period is the month of the usage as YYYY-MM (UTC)
+	Period string
+}
+
+// Usage is the number of model tokens used
+type Usage struct {
+	InputTokens  int64
+	OutputTokens int64
+}
+
+// Period returns the month t falls in, as YYYY-MM (UTC)
+func Period(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// UsageRecorder records collected usage, e.g. in the status of budgets. It deletes
+// each key from usage once recorded, so that if it returns an error only the usage
+// left is recorded again
+type UsageRecorder func(ctx context.Context, usage map[UsageKey]Usage) error
+
+// UsageCollector consumes the usage topic and hands the usage collected over every
+// flush interval to a recorder. It subscribes again when the connection changes
+type UsageCollector struct {
+	conn   *Connection
+	topic  string
+	record UsageRecorder
+
+	// restart is signalled once the consumer of the previous client is stopped
+	restart chan struct{}
+
+	mu sync.Mutex
+	// stopRun stops the consumer of the current client and waits for it
+	stopRun func()
+}
+
+// NewUsageCollector creates a collector of the usage reported on topic
+func NewUsageCollector(conn *Connection, topic string, record UsageRecorder) *UsageCollector {
+	c := &UsageCollector{conn: conn, topic: topic, record: record, restart: make(chan struct{}, 1)}
+	conn.onChange(c.reconnect)
+	return c
+}
+
+// reconnect stops consuming through the previous client and has Start subscribe
+// through the new one
+func (c *UsageCollector) reconnect(pulsar.Client) {
+	c.mu.Lock()
+	stop := c.stopRun
+	c.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+	select {
+	case c.restart <- struct{}{}:
+	default:
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so that usage is
+// only counted once
+func (c *UsageCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable. It consumes through the current client of the
+// connection, and waits while the connection has none
+func (c *UsageCollector) Start(ctx context.Context) error {
+	for {
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		c.mu.Lock()
+		client := c.conn.Client()
+		c.stopRun = func() {
+			cancel()
+			<-done
+		}
+		c.mu.Unlock()
+
+		go func() {
+			defer close(done)
+			if client != nil {
+				c.consume(runCtx, client)
+			} else {
+				<-runCtx.Done()
+			}
+		}()
+
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-done
+			return nil
+		case <-c.restart:
+			cancel()
+			<-done
+		}
+	}
+}
+
+// consume collects the usage reported on the topic until ctx is done. Messages are
+// acknowledged once their usage is recorded, so they are consumed again after a failure
+func (c *UsageCollector) consume(ctx context.Context, client pulsar.Client) {
+	log := logf.Log.WithName("usage-collector").WithValues("topic", c.topic)
+
+	var consumer pulsar.Consumer
+	for {
+		var err error
+		consumer, err = client.Subscribe(pulsar.ConsumerOptions{
+			Topic:                       c.topic,
+			SubscriptionName:            usageSubscription,
+			Type:                        pulsar.Failover,
+			SubscriptionInitialPosition: pulsar.SubscriptionPositionEarliest,
+		})
+		if err == nil {
+			break
+		}
+		log.Error(err, "Failed to subscribe to usage topic, retrying")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(usageFlushInterval):
+		}
+	}
+	defer consumer.Close()
+
+	pending := map[UsageKey]Usage{}
+	var last pulsar.Message
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case cm := <-consumer.Chan():
+			last = cm.Message
+			key, usage, err := parseUsageReport(cm.Payload(), cm.PublishTime())
+			if err != nil {
+				log.Error(err, "Skipping usage report")
+				continue
+			}
+			total := pending[key]
+			total.InputTokens += usage.InputTokens
+			total.OutputTokens += usage.OutputTokens
+			pending[key] = total
+		case <-ticker.C:
+			if last == nil {
+				continue
+			}
+			if len(pending) > 0 {
+				if err := c.record(ctx, pending); err != nil {
+					log.Error(err, "Failed to record usage, retrying")
+					continue
+				}
+			}
+			if err := consumer.AckCumulative(last); err != nil {
+				log.Error(err, "Failed to acknowledge usage reports")
+			}
+			pending = map[UsageKey]Usage{}
+			last = nil
+		}
+	}
+}
+
+// parseUsageReport decodes a report published at publishTime
+func parseUsageReport(payload []byte, publishTime time.Time) (UsageKey, Usage, error) {
+	var report UsageReport
+	if err := json.Unmarshal(payload, &report); err != nil {
+		return UsageKey{}, Usage{}, fmt.Errorf("invalid usage report: %v", err)
+	}
+	if report.Namespace == "" || report.Agent == "" {
+		return UsageKey{}, Usage{}, fmt.Errorf("invalid usage report: no namespace or agent")
+	}
+	key := UsageKey{
+		Agent:  types.NamespacedName{Namespace: report.Namespace, Name: report.Agent},
+		Period: Period(publishTime),
+	}
+	return key, Usage{InputTokens: report.InputTokens, OutputTokens: report.OutputTokens}, nil
+}
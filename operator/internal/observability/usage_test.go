@@ -0,0 +1,43 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package observability
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Usage reports", func() {
+	It("Should parse the reports of the agent runtime into the month they were published in", func() {
+		publishTime := time.Date(2025, 1, 31, 23, 59, 0, 0, time.FixedZone("UTC-1", -3600))
+		key, usage, err := parseUsageReport([]byte(`{"namespace": "default", "agent": "my-agent", "model": "gemini-2.0-flash", "inputTokens": 120, "outputTokens": 30}`), publishTime)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key).To(Equal(UsageKey{
+			Agent:  types.NamespacedName{Namespace: "default", Name: "my-agent"},
+			Period: "2025-02",
+		}))
+		Expect(usage).To(Equal(Usage{InputTokens: 120, OutputTokens: 30}))
+
+		_, _, err = parseUsageReport([]byte(`{"inputTokens": 120}`), publishTime)
+		Expect(err).To(HaveOccurred())
+		_, _, err = parseUsageReport([]byte(`not json`), publishTime)
+		Expect(err).To(HaveOccurred())
+	})
+})